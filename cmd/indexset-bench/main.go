@@ -0,0 +1,138 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/friedenberg/indexset"
+	"github.com/friedenberg/indexset/internal/benchmark"
+)
+
+func main() {
+	size := flag.Int("size", 1000, "number of ranges in each workload")
+	seed := flag.Int64("seed", 1, "seed for the workload generator")
+	implementationsFlag := flag.String("implementations", "", "comma separated implementations to compare (default all)")
+	operationsFlag := flag.String("operations", "", "comma separated operations to run (default all)")
+	workloadsFlag := flag.String("workloads", "", "comma separated workloads to generate (default all)")
+	flag.Parse()
+
+	if *size < 1 {
+		exitWithError(fmt.Errorf("size must be at least 1, got %d", *size))
+	}
+
+	implementations := indexset.Implementations()
+	implementationNames := selected(*implementationsFlag, keys(implementations))
+
+	for _, name := range implementationNames {
+		if _, ok := implementations[name]; !ok {
+			exitWithError(fmt.Errorf("unknown implementation: %s", name))
+		}
+	}
+
+	operations := make([]benchmark.Operation, 0)
+	operationNames := make([]string, 0)
+
+	for _, operation := range benchmark.Operations() {
+		operationNames = append(operationNames, operation.Name)
+	}
+
+	for _, name := range selected(*operationsFlag, operationNames) {
+		found := false
+
+		for _, operation := range benchmark.Operations() {
+			if operation.Name == name {
+				operations = append(operations, operation)
+				found = true
+			}
+		}
+
+		if !found {
+			exitWithError(fmt.Errorf("unknown operation: %s", name))
+		}
+	}
+
+	workloads := make([]indexset.Workload, 0)
+	workloadNames := make([]string, 0)
+
+	for _, workload := range indexset.Workloads() {
+		workloadNames = append(workloadNames, workload.String())
+	}
+
+	for _, name := range selected(*workloadsFlag, workloadNames) {
+		found := false
+
+		for _, workload := range indexset.Workloads() {
+			if workload.String() == name {
+				workloads = append(workloads, workload)
+				found = true
+			}
+		}
+
+		if !found {
+			exitWithError(fmt.Errorf("unknown workload: %s", name))
+		}
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+	header := []string{"operation", "workload"}
+
+	for _, name := range implementationNames {
+		header = append(header, name+" ns/op")
+	}
+
+	fmt.Fprintf(writer, "%s\t\n", strings.Join(header, "\t"))
+
+	for _, operation := range operations {
+		for _, workload := range workloads {
+			ranges := indexset.GenerateWorkload(workload, *size, *seed)
+			row := []string{operation.Name, workload.String()}
+
+			for _, name := range implementationNames {
+				implementation := implementations[name]
+
+				result := testing.Benchmark(
+					func(b *testing.B) {
+						operation.Run(b, implementation, ranges)
+					},
+				)
+
+				row = append(row, fmt.Sprintf("%d", result.NsPerOp()))
+			}
+
+			fmt.Fprintf(writer, "%s\t\n", strings.Join(row, "\t"))
+		}
+	}
+
+	writer.Flush()
+}
+
+func keys(m map[string]func() indexset.Implementation) []string {
+	names := make([]string, 0, len(m))
+
+	for name := range m {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func selected(flagValue string, all []string) []string {
+	if flagValue == "" {
+		return all
+	}
+
+	return strings.Split(flagValue, ",")
+}
+
+func exitWithError(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
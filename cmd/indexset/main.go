@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/friedenberg/indexset"
+)
+
+type command struct {
+	usage string
+	args  int
+	// interactive commands read stdin themselves, so their set starts out
+	// empty unless files are given
+	interactive bool
+	// flags, if set, is parsed from the arguments following the command name
+	flags *flag.FlagSet
+	run   func(w io.Writer, s *indexset.Set, args []string) error
+}
+
+func commands() map[string]command {
+	return map[string]command{
+		"max": {
+			usage: "max [file...]",
+			run: func(w io.Writer, s *indexset.Set, _ []string) error {
+				_, err := fmt.Fprintln(w, s.Max())
+				return err
+			},
+		},
+		"dump": {
+			usage: "dump [file...]",
+			run: func(w io.Writer, s *indexset.Set, _ []string) error {
+				return indexset.WriteRanges(w, s)
+			},
+		},
+		"query": {
+			usage: "query <index> [file...]",
+			args:  1,
+			run: func(w io.Writer, s *indexset.Set, args []string) error {
+				index, err := strconv.ParseInt(args[0], 10, 64)
+
+				if err != nil {
+					return fmt.Errorf("invalid index: %w", err)
+				}
+
+				_, err = fmt.Fprintln(w, s.At(index))
+				return err
+			},
+		},
+		"gaps": {
+			usage: "gaps [file...]",
+			run: func(w io.Writer, s *indexset.Set, _ []string) error {
+				first, last := s.Nth(0), lastMember(s)
+
+				if first == nil {
+					return nil
+				}
+
+				window, err := indexset.MakeRange(first.IndexRange().Left(), last.IndexRange().Right(), 0)
+
+				if err != nil {
+					return err
+				}
+
+				gaps, err := s.Gaps(*window)
+
+				if err != nil {
+					return err
+				}
+
+				for _, gap := range gaps {
+					if _, err := fmt.Fprintf(w, "%d %d\n", gap.Left(), gap.Right()); err != nil {
+						return err
+					}
+				}
+
+				return nil
+			},
+		},
+		"render": renderCommand(),
+		"svg":    svgCommand(),
+		"dot": {
+			usage: "dot [file...]",
+			run: func(w io.Writer, s *indexset.Set, _ []string) error {
+				return s.WriteDOT(w)
+			},
+		},
+		"repl": {
+			usage:       "repl [file...]",
+			interactive: true,
+			run:         runRepl,
+		},
+	}
+}
+
+func main() {
+	implementationName := flag.String("implementation", "persistent_tree", "implementation backing the set")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands()[flag.Arg(0)]
+
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	args := flag.Args()[1:]
+
+	if cmd.flags != nil {
+		if err := cmd.flags.Parse(args); err != nil {
+			os.Exit(2)
+		}
+
+		args = cmd.flags.Args()
+	}
+
+	if len(args) < cmd.args {
+		fmt.Fprintf(os.Stderr, "usage: indexset %s\n", cmd.usage)
+		os.Exit(2)
+	}
+
+	newImplementation, ok := indexset.Implementations()[*implementationName]
+
+	if !ok {
+		exitWithError(fmt.Errorf("unknown implementation: %s", *implementationName))
+	}
+
+	set := &indexset.Set{Implementation: newImplementation()}
+
+	if paths := args[cmd.args:]; len(paths) > 0 || !cmd.interactive {
+		if err := readSet(set, paths); err != nil {
+			exitWithError(err)
+		}
+	}
+
+	writer := bufio.NewWriter(os.Stdout)
+
+	if err := cmd.run(writer, set, args[:cmd.args]); err != nil {
+		exitWithError(err)
+	}
+
+	if err := writer.Flush(); err != nil {
+		exitWithError(err)
+	}
+}
+
+// readSet adds the ranges of every file to s, or of stdin if there are no
+// files or a file is named "-".
+func readSet(s *indexset.Set, paths []string) error {
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+
+	for _, path := range paths {
+		var reader io.Reader = os.Stdin
+
+		if path != "-" {
+			file, err := os.Open(path)
+
+			if err != nil {
+				return err
+			}
+
+			defer file.Close()
+			reader = file
+		}
+
+		ranges, err := indexset.ReadRanges(reader)
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		for _, r := range ranges {
+			if err := s.Add(r); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func lastMember(s *indexset.Set) indexset.Member {
+	var last indexset.Member
+
+	s.Do(
+		func(m indexset.Member) bool {
+			last = m
+			return false
+		},
+	)
+
+	return last
+}
+
+func renderCommand() command {
+	options := indexset.RenderOptions{}
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	flags.IntVar(&options.Width, "width", 80, "number of columns")
+	flags.IntVar(&options.Height, "height", 10, "number of rows")
+	flags.BoolVar(&options.Unicode, "unicode", false, "draw with block characters")
+	flags.Func(
+		"window",
+		"only draw the coordinates `left:right`",
+		func(value string) error {
+			left, right, err := parseWindow(value)
+
+			if err != nil {
+				return err
+			}
+
+			options.Window, err = indexset.MakeRange(left, right, 0)
+			return err
+		},
+	)
+
+	return command{
+		usage: "render [-width n] [-height n] [-window left:right] [-unicode] [file...]",
+		flags: flags,
+		run: func(w io.Writer, s *indexset.Set, _ []string) error {
+			return s.Render(w, options)
+		},
+	}
+}
+
+func svgCommand() command {
+	options := indexset.SVGOptions{}
+	flags := flag.NewFlagSet("svg", flag.ContinueOnError)
+	flags.IntVar(&options.Width, "width", 640, "image width in pixels")
+	flags.IntVar(&options.Height, "height", 240, "image height in pixels")
+	flags.Func(
+		"window",
+		"only draw the coordinates `left:right`",
+		func(value string) error {
+			left, right, err := parseWindow(value)
+
+			if err != nil {
+				return err
+			}
+
+			options.Window, err = indexset.MakeRange(left, right, 0)
+			return err
+		},
+	)
+
+	return command{
+		usage: "svg [-width n] [-height n] [-window left:right] [file...]",
+		flags: flags,
+		run: func(w io.Writer, s *indexset.Set, _ []string) error {
+			return s.WriteSVG(w, options)
+		},
+	}
+}
+
+// parseWindow parses the value of a -window flag. The caller passes the
+// bounds to indexset.MakeRange.
+func parseWindow(value string) (left, right int64, err error) {
+	leftValue, rightValue, ok := strings.Cut(value, ":")
+
+	if !ok {
+		return 0, 0, errors.New("expected left:right")
+	}
+
+	numbers, err := parseNumbers([]string{leftValue, rightValue}, 2)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return numbers[0], numbers[1], nil
+}
+
+func usage() {
+	names := make([]string, 0)
+
+	for name := range commands() {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "usage: indexset [-implementation name] <command> [args] [file...]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s\n", commands()[name].usage)
+	}
+
+	fmt.Fprintln(os.Stderr, "\nflags:")
+	flag.PrintDefaults()
+}
+
+func exitWithError(err error) {
+	fmt.Fprintln(os.Stderr, err)
+
+	if errors.Is(err, indexset.ErrInvalidRange) {
+		os.Exit(2)
+	}
+
+	os.Exit(1)
+}
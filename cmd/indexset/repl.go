@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/friedenberg/indexset"
+	"golang.org/x/term"
+)
+
+const replHelp = `commands:
+  add <left> <right> <weight>  add a range
+  sub <left> <right> <weight>  subtract a range
+  max                          print the largest weight
+  at <index>                   print the weight at index
+  show                         print every segment
+  undo                         revert the last add, sub or load
+  redo                         apply the last undone command again
+  save <file>                  write the segments to file
+  load <file>                  replace the set with the ranges in file
+  history                      print the commands entered so far
+  help                         print this message
+  quit                         leave the shell`
+
+type lineReader interface {
+	ReadLine() (string, error)
+}
+
+type scannerLineReader struct {
+	*bufio.Scanner
+}
+
+func (r scannerLineReader) ReadLine() (string, error) {
+	if !r.Scan() {
+		if err := r.Err(); err != nil {
+			return "", err
+		}
+
+		return "", io.EOF
+	}
+
+	return r.Text(), nil
+}
+
+type repl struct {
+	set     *indexset.Set
+	out     io.Writer
+	history []string
+}
+
+func newRepl(s *indexset.Set, w io.Writer) *repl {
+	if s.History == nil {
+		s.History = &indexset.History{}
+	}
+
+	return &repl{set: s, out: w}
+}
+
+func runRepl(w io.Writer, s *indexset.Set, _ []string) error {
+	r := newRepl(s, w)
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return r.run(scannerLineReader{bufio.NewScanner(os.Stdin)})
+	}
+
+	state, err := term.MakeRaw(int(os.Stdin.Fd()))
+
+	if err != nil {
+		return err
+	}
+
+	defer term.Restore(int(os.Stdin.Fd()), state)
+
+	terminal := term.NewTerminal(
+		struct {
+			io.Reader
+			io.Writer
+		}{os.Stdin, os.Stdout},
+		"> ",
+	)
+
+	r.out = terminal
+
+	return r.run(terminal)
+}
+
+func (r *repl) run(reader lineReader) error {
+	for {
+		line, err := reader.ReadLine()
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+
+		if line == "" {
+			continue
+		}
+
+		r.history = append(r.history, line)
+
+		quit, err := r.execute(strings.Fields(line))
+
+		if err != nil {
+			fmt.Fprintf(r.out, "error: %s\n", err)
+		}
+
+		if quit {
+			return nil
+		}
+
+		if flusher, ok := r.out.(interface{ Flush() error }); ok {
+			flusher.Flush()
+		}
+	}
+}
+
+func (r *repl) execute(fields []string) (quit bool, err error) {
+	command, args := fields[0], fields[1:]
+
+	switch command {
+	case "add", "sub":
+		numbers, err := parseNumbers(args, 3)
+
+		if err != nil {
+			return false, err
+		}
+
+		newRange, err := indexset.MakeRange(numbers[0], numbers[1], numbers[2])
+
+		if err != nil {
+			return false, err
+		}
+
+		return false, r.mutate(
+			func() error {
+				if command == "sub" {
+					return r.set.Subtract(*newRange)
+				}
+
+				return r.set.Add(*newRange)
+			},
+		)
+
+	case "max":
+		_, err = fmt.Fprintln(r.out, r.set.Max())
+
+	case "at":
+		numbers, err := parseNumbers(args, 1)
+
+		if err != nil {
+			return false, err
+		}
+
+		_, err = fmt.Fprintln(r.out, r.set.At(numbers[0]))
+
+		return false, err
+
+	case "show":
+		_, err = fmt.Fprintln(r.out, r.set)
+
+	case "undo", "redo":
+		if command == "undo" {
+			err = r.set.Undo()
+		} else {
+			err = r.set.Redo()
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		_, err = fmt.Fprintln(r.out, r.set)
+
+	case "save":
+		if len(args) != 1 {
+			return false, errors.New("usage: save <file>")
+		}
+
+		err = r.save(args[0])
+
+	case "load":
+		if len(args) != 1 {
+			return false, errors.New("usage: load <file>")
+		}
+
+		err = r.mutate(func() error { return r.load(args[0]) })
+
+	case "history":
+		for i, line := range r.history {
+			fmt.Fprintf(r.out, "%4d  %s\n", i+1, line)
+		}
+
+	case "help":
+		_, err = fmt.Fprintln(r.out, replHelp)
+
+	case "quit", "exit":
+		return true, nil
+
+	default:
+		err = fmt.Errorf("unknown command: %s (try help)", command)
+	}
+
+	return false, err
+}
+
+// mutate applies f in a transaction, so that it is undone as a whole, and
+// prints the result. If f fails the set is left as it was.
+func (r *repl) mutate(f func() error) error {
+	tx := r.set.Begin()
+
+	if err := f(); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return rollbackErr
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintln(r.out, r.set)
+	return err
+}
+
+func (r *repl) clear() error {
+	members := make([]indexset.Member, 0)
+
+	err := r.set.Do(
+		func(m indexset.Member) bool {
+			members = append(members, m)
+			return false
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	for _, m := range members {
+		if err := r.set.Replace(m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *repl) save(path string) error {
+	file, err := os.Create(path)
+
+	if err != nil {
+		return err
+	}
+
+	if err := indexset.WriteRanges(file, r.set); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func (r *repl) load(path string) error {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	ranges, err := indexset.ReadRanges(file)
+
+	if err != nil {
+		return err
+	}
+
+	if err := r.clear(); err != nil {
+		return err
+	}
+
+	for _, ir := range ranges {
+		if err := r.set.Add(ir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func parseNumbers(args []string, count int) ([]int64, error) {
+	if len(args) != count {
+		return nil, fmt.Errorf("expected %d numbers, got %d", count, len(args))
+	}
+
+	numbers := make([]int64, count)
+
+	for i, arg := range args {
+		number, err := strconv.ParseInt(arg, 10, 64)
+
+		if err != nil {
+			return nil, err
+		}
+
+		numbers[i] = number
+	}
+
+	return numbers, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/friedenberg/indexset"
+	"github.com/stretchr/testify/assert"
+)
+
+func runReplScript(t *testing.T, script string) string {
+	var out bytes.Buffer
+
+	r := newRepl(&indexset.Set{Implementation: indexset.NewPersistentTree()}, &out)
+
+	assert.Nil(t, r.run(scannerLineReader{bufio.NewScanner(strings.NewReader(script))}))
+
+	return out.String()
+}
+
+func TestRepl(t *testing.T) {
+	out := runReplScript(
+		t,
+		`add 1 5 3
+sub 2 4 1
+max
+at 3
+undo
+at 3
+undo
+undo
+redo
+redo
+redo
+bogus
+show
+`,
+	)
+
+	assert.Equal(
+		t,
+		strings.Join(
+			[]string{
+				"\n3:|1_5|",
+				"\n3:|1_1|2:|2_4|3:|5_5|",
+				"3",
+				"2",
+				"\n3:|1_5|",
+				"3",
+				"\n",
+				"error: nothing to undo",
+				"\n3:|1_5|",
+				"\n3:|1_1|2:|2_4|3:|5_5|",
+				"error: nothing to redo",
+				"error: unknown command: bogus (try help)",
+				"\n3:|1_1|2:|2_4|3:|5_5|",
+				"",
+			},
+			"\n",
+		),
+		out,
+	)
+}
+
+func TestReplSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "set.txt")
+
+	runReplScript(t, "add 1 5 3\nadd 4 8 1\nsave "+path+"\n")
+
+	out := runReplScript(t, "add 20 30 1\nload "+path+"\nmax\nundo\nquit\nmax\n")
+	assert.Equal(t, "\n1:|20_30|\n\n3:|1_3|4:|4_5|1:|6_8|\n4\n\n1:|20_30|\n", out)
+}
@@ -0,0 +1,34 @@
+package indexset_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/friedenberg/indexset"
+	"github.com/friedenberg/indexset/indexsettest"
+)
+
+func TestConformanceLinkedList(t *testing.T) {
+	indexsettest.RunConformance(t, indexset.NewLinkedList)
+}
+
+func TestConformancePersistentTree(t *testing.T) {
+	indexsettest.RunConformance(t, indexset.NewPersistentTree)
+}
+
+func TestConformanceDiskTree(t *testing.T) {
+	indexsettest.RunConformance(
+		t,
+		func() indexset.Implementation {
+			tree, err := indexset.OpenDiskTree(filepath.Join(t.TempDir(), "tree"))
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			t.Cleanup(func() { tree.Close() })
+
+			return tree
+		},
+	)
+}
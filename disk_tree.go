@@ -0,0 +1,737 @@
+package indexset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+const (
+	diskTreeDefaultPageSize = 4096
+
+	//header page: magic, page size, root, page count
+	diskHeaderPageSize  = 8
+	diskHeaderRoot      = 16
+	diskHeaderPageCount = 24
+
+	//every other page: kind, count, prev, next, entries
+	diskPageKind       = 0
+	diskPageCount      = 2
+	diskPagePrev       = 8
+	diskPageNext       = 16
+	diskPageHeaderSize = 24
+
+	diskLeafEntrySize     = 3 * 8
+	diskInternalEntrySize = 2 * 8
+
+	diskPageLeaf     = byte(1)
+	diskPageInternal = byte(2)
+
+	//the leftmost leaf never moves, since splits move the upper half of a
+	//page into a new one
+	diskFirstLeaf = uint64(1)
+)
+
+var diskTreeMagic = [8]byte{'I', 'X', 'S', 'E', 'T', 'B', 'T', '1'}
+
+// DiskTree is an Implementation backed by a B+tree in a memory-mapped file,
+// for sets that do not fit in the heap. Leaves hold the segments in order
+// and are linked both ways, internal pages hold the smallest left of each
+// child. Pages emptied by Replace stay in the tree and the file never
+// shrinks. Writes go to the page cache and reach the disk on Sync or Close
+// but are not crash-safe; wrap the set in a DurableSet or copy the file
+// while it is closed for that. A DiskTree is not safe for concurrent use.
+type DiskTree struct {
+	file     *os.File
+	data     []byte
+	pageSize int
+}
+
+// OpenDiskTree opens the tree in the file at path, creating an empty one if
+// the file does not exist or is empty.
+func OpenDiskTree(path string) (*DiskTree, error) {
+	return openDiskTree(path, diskTreeDefaultPageSize)
+}
+
+func openDiskTree(path string, pageSize int) (*DiskTree, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+
+	if err != nil {
+		return nil, err
+	}
+
+	t := &DiskTree{file: file, pageSize: pageSize}
+
+	if err := t.open(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return t, nil
+}
+
+func (t *DiskTree) open() error {
+	info, err := t.file.Stat()
+
+	if err != nil {
+		return err
+	}
+
+	if info.Size() == 0 {
+		if err := t.remap(2 * t.pageSize); err != nil {
+			return err
+		}
+
+		copy(t.data, diskTreeMagic[:])
+		binary.LittleEndian.PutUint64(t.data[diskHeaderPageSize:], uint64(t.pageSize))
+		t.setRoot(diskFirstLeaf)
+		t.setPageCount(2)
+		t.page(diskFirstLeaf)[diskPageKind] = diskPageLeaf
+
+		return nil
+	}
+
+	header := make([]byte, diskHeaderPageSize+8)
+
+	if _, err := t.file.ReadAt(header, 0); err != nil {
+		return fmt.Errorf("%w: reading header: %s", ErrCorruptDiskTree, err)
+	}
+
+	if !bytes.Equal(header[:8], diskTreeMagic[:]) {
+		return fmt.Errorf("%w: bad magic", ErrCorruptDiskTree)
+	}
+
+	t.pageSize = int(binary.LittleEndian.Uint64(header[diskHeaderPageSize:]))
+
+	if t.pageSize < diskPageHeaderSize+4*diskLeafEntrySize || info.Size()%int64(t.pageSize) != 0 {
+		return fmt.Errorf("%w: page size %d does not fit file size %d", ErrCorruptDiskTree, t.pageSize, info.Size())
+	}
+
+	if err := t.remap(int(info.Size())); err != nil {
+		return err
+	}
+
+	if count := t.pageCount(); count < 2 || count*uint64(t.pageSize) > uint64(len(t.data)) || t.root() >= count {
+		return fmt.Errorf("%w: %d pages, root %d", ErrCorruptDiskTree, count, t.root())
+	}
+
+	return nil
+}
+
+// remap grows the file to size if needed and maps it again. Pages returned
+// by page before a remap must not be used after it.
+func (t *DiskTree) remap(size int) error {
+	if t.data != nil {
+		if err := munmap(t.data); err != nil {
+			return err
+		}
+
+		t.data = nil
+	}
+
+	info, err := t.file.Stat()
+
+	if err != nil {
+		return err
+	}
+
+	if info.Size() < int64(size) {
+		if err := t.file.Truncate(int64(size)); err != nil {
+			return err
+		}
+	}
+
+	data, err := mmap(t.file, size)
+
+	if err != nil {
+		return err
+	}
+
+	t.data = data
+	return nil
+}
+
+// Sync writes the mapped pages back to the file.
+func (t *DiskTree) Sync() error {
+	return msync(t.data)
+}
+
+// Close syncs and unmaps the file. The tree must not be used afterwards.
+func (t *DiskTree) Close() error {
+	err := t.Sync()
+
+	if unmapErr := munmap(t.data); err == nil {
+		err = unmapErr
+	}
+
+	t.data = nil
+
+	if closeErr := t.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func (t *DiskTree) root() uint64 {
+	return binary.LittleEndian.Uint64(t.data[diskHeaderRoot:])
+}
+
+func (t *DiskTree) setRoot(id uint64) {
+	binary.LittleEndian.PutUint64(t.data[diskHeaderRoot:], id)
+}
+
+func (t *DiskTree) pageCount() uint64 {
+	return binary.LittleEndian.Uint64(t.data[diskHeaderPageCount:])
+}
+
+func (t *DiskTree) setPageCount(count uint64) {
+	binary.LittleEndian.PutUint64(t.data[diskHeaderPageCount:], count)
+}
+
+func (t *DiskTree) page(id uint64) diskPage {
+	offset := int(id) * t.pageSize
+	return diskPage(t.data[offset : offset+t.pageSize])
+}
+
+// allocate returns a new zeroed page, doubling the file if it is full.
+func (t *DiskTree) allocate(kind byte) (uint64, error) {
+	id := t.pageCount()
+
+	if int(id+1)*t.pageSize > len(t.data) {
+		if err := t.remap(2 * len(t.data)); err != nil {
+			return 0, err
+		}
+	}
+
+	t.setPageCount(id + 1)
+	t.page(id)[diskPageKind] = kind
+
+	return id, nil
+}
+
+func (t *DiskTree) leafCapacity() int {
+	return (t.pageSize - diskPageHeaderSize) / diskLeafEntrySize
+}
+
+func (t *DiskTree) internalCapacity() int {
+	return (t.pageSize - diskPageHeaderSize) / diskInternalEntrySize
+}
+
+type diskPage []byte
+
+func (p diskPage) kind() byte {
+	return p[diskPageKind]
+}
+
+func (p diskPage) count() int {
+	return int(binary.LittleEndian.Uint16(p[diskPageCount:]))
+}
+
+func (p diskPage) setCount(count int) {
+	binary.LittleEndian.PutUint16(p[diskPageCount:], uint16(count))
+}
+
+func (p diskPage) prev() uint64 {
+	return binary.LittleEndian.Uint64(p[diskPagePrev:])
+}
+
+func (p diskPage) setPrev(id uint64) {
+	binary.LittleEndian.PutUint64(p[diskPagePrev:], id)
+}
+
+func (p diskPage) next() uint64 {
+	return binary.LittleEndian.Uint64(p[diskPageNext:])
+}
+
+func (p diskPage) setNext(id uint64) {
+	binary.LittleEndian.PutUint64(p[diskPageNext:], id)
+}
+
+func (p diskPage) segment(i int) indexRange {
+	entry := p[diskPageHeaderSize+i*diskLeafEntrySize:]
+
+	return indexRange{
+		left:   int64(binary.LittleEndian.Uint64(entry[0:])),
+		right:  int64(binary.LittleEndian.Uint64(entry[8:])),
+		weight: int64(binary.LittleEndian.Uint64(entry[16:])),
+	}
+}
+
+func (p diskPage) setSegment(i int, r indexRange) {
+	entry := p[diskPageHeaderSize+i*diskLeafEntrySize:]
+	binary.LittleEndian.PutUint64(entry[0:], uint64(r.left))
+	binary.LittleEndian.PutUint64(entry[8:], uint64(r.right))
+	binary.LittleEndian.PutUint64(entry[16:], uint64(r.weight))
+}
+
+func (p diskPage) segments() []indexRange {
+	segments := make([]indexRange, p.count())
+
+	for i := range segments {
+		segments[i] = p.segment(i)
+	}
+
+	return segments
+}
+
+func (p diskPage) setSegments(segments []indexRange) {
+	for i, r := range segments {
+		p.setSegment(i, r)
+	}
+
+	p.setCount(len(segments))
+}
+
+// diskChild is an entry of an internal page. Every segment below child has
+// a left of at least key.
+type diskChild struct {
+	key   int64
+	child uint64
+}
+
+func (p diskPage) child(i int) diskChild {
+	entry := p[diskPageHeaderSize+i*diskInternalEntrySize:]
+
+	return diskChild{
+		key:   int64(binary.LittleEndian.Uint64(entry[0:])),
+		child: binary.LittleEndian.Uint64(entry[8:]),
+	}
+}
+
+func (p diskPage) setChild(i int, c diskChild) {
+	entry := p[diskPageHeaderSize+i*diskInternalEntrySize:]
+	binary.LittleEndian.PutUint64(entry[0:], uint64(c.key))
+	binary.LittleEndian.PutUint64(entry[8:], c.child)
+}
+
+func (p diskPage) children() []diskChild {
+	children := make([]diskChild, p.count())
+
+	for i := range children {
+		children[i] = p.child(i)
+	}
+
+	return children
+}
+
+func (p diskPage) setChildren(children []diskChild) {
+	for i, c := range children {
+		p.setChild(i, c)
+	}
+
+	p.setCount(len(children))
+}
+
+// childFor returns the index of the last child whose key is at most left,
+// or 0 if there is none.
+func (p diskPage) childFor(left int64) int {
+	i := sort.Search(p.count(), func(i int) bool { return p.child(i).key > left })
+
+	if i == 0 {
+		return 0
+	}
+
+	return i - 1
+}
+
+func (t *DiskTree) leafFor(left int64) uint64 {
+	id := t.root()
+
+	for {
+		p := t.page(id)
+
+		if p.kind() != diskPageInternal {
+			return id
+		}
+
+		id = p.child(p.childFor(left)).child
+	}
+}
+
+// seek returns the position of the first segment whose right is at least
+// index.
+func (t *DiskTree) seek(index int64) (uint64, int) {
+	id := t.leafFor(index)
+	p := t.page(id)
+
+	//only a segment at the end of an earlier leaf can cover index if no
+	//segment in this leaf starts at or before it
+	if p.count() == 0 || p.segment(0).left > index {
+		for prev := p.prev(); prev != 0; prev = t.page(prev).prev() {
+			pp := t.page(prev)
+
+			if count := pp.count(); count > 0 {
+				if pp.segment(count-1).right >= index {
+					return prev, count - 1
+				}
+
+				break
+			}
+		}
+	}
+
+	return id, sort.Search(p.count(), func(i int) bool { return p.segment(i).right >= index })
+}
+
+// scan calls f with every segment from the position on, following the leaf
+// links.
+func (t *DiskTree) scan(id uint64, i int, f func(indexRange) (stop bool)) {
+	for id != 0 {
+		p := t.page(id)
+
+		for ; i < p.count(); i++ {
+			if f(p.segment(i)) {
+				return
+			}
+		}
+
+		id, i = p.next(), 0
+	}
+}
+
+// diskMember identifies a segment by value, since segments move between
+// pages on every split.
+type diskMember indexRange
+
+func (m diskMember) IndexRange() indexRange {
+	return indexRange(m)
+}
+
+func (t *DiskTree) FindOverlapping(overlap indexRange) []Member {
+	overlapping := make([]Member, 0)
+	id, i := t.seek(overlap.left)
+
+	t.scan(
+		id,
+		i,
+		func(r indexRange) bool {
+			if r.left > overlap.right {
+				return true
+			}
+
+			overlapping = append(overlapping, diskMember(r))
+			return false
+		},
+	)
+
+	return overlapping
+}
+
+func (t *DiskTree) Replace(original Member, replacements ...indexRange) error {
+	if err := t.remove(original.IndexRange()); err != nil {
+		return err
+	}
+
+	for _, r := range replacements {
+		if err := t.insert(r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *DiskTree) AddOrFindOverlapping(newRange indexRange) ([]Member, error) {
+	overlapping := t.FindOverlapping(newRange)
+
+	if len(overlapping) > 0 {
+		return overlapping, nil
+	}
+
+	return nil, t.insert(newRange)
+}
+
+func (t *DiskTree) Do(f func(Member) (stop bool)) error {
+	checker := segmentChecker{op: "do"}
+	var err error
+
+	t.scan(
+		diskFirstLeaf,
+		0,
+		func(r indexRange) bool {
+			if err = checker.check(r); err != nil {
+				return true
+			}
+
+			return f(diskMember(r))
+		},
+	)
+
+	return err
+}
+
+func (t *DiskTree) remove(r indexRange) error {
+	p := t.page(t.leafFor(r.left))
+	segments := p.segments()
+	i := sort.Search(len(segments), func(i int) bool { return segments[i].left >= r.left })
+
+	if i == len(segments) || segments[i] != r {
+		return &RangeError{Op: "replace", Ranges: []indexRange{r}, Err: ErrMemberNotFound}
+	}
+
+	p.setSegments(append(segments[:i], segments[i+1:]...))
+
+	return nil
+}
+
+func (t *DiskTree) insert(r indexRange) error {
+	split, err := t.insertBelow(t.root(), r)
+
+	if err != nil || split == nil {
+		return err
+	}
+
+	root, err := t.allocate(diskPageInternal)
+
+	if err != nil {
+		return err
+	}
+
+	t.page(root).setChildren([]diskChild{{math.MinInt64, t.root()}, *split})
+	t.setRoot(root)
+
+	return nil
+}
+
+// insertBelow adds r to the subtree at id and returns the entry for the new
+// right sibling of id if id had to be split.
+func (t *DiskTree) insertBelow(id uint64, r indexRange) (*diskChild, error) {
+	p := t.page(id)
+
+	if p.kind() != diskPageInternal {
+		segments := p.segments()
+		i := sort.Search(len(segments), func(i int) bool { return segments[i].left > r.left })
+		segments = append(segments[:i], append([]indexRange{r}, segments[i:]...)...)
+
+		if len(segments) <= t.leafCapacity() {
+			p.setSegments(segments)
+			return nil, nil
+		}
+
+		return t.splitLeaf(id, segments)
+	}
+
+	i := p.childFor(r.left)
+	split, err := t.insertBelow(p.child(i).child, r)
+
+	if err != nil || split == nil {
+		return nil, err
+	}
+
+	//the child may have remapped the file
+	p = t.page(id)
+	children := p.children()
+	children = append(children[:i+1], append([]diskChild{*split}, children[i+1:]...)...)
+
+	if len(children) <= t.internalCapacity() {
+		p.setChildren(children)
+		return nil, nil
+	}
+
+	sibling, err := t.allocate(diskPageInternal)
+
+	if err != nil {
+		return nil, err
+	}
+
+	half := len(children) / 2
+	t.page(id).setChildren(children[:half])
+	t.page(sibling).setChildren(children[half:])
+
+	return &diskChild{children[half].key, sibling}, nil
+}
+
+func (t *DiskTree) splitLeaf(id uint64, segments []indexRange) (*diskChild, error) {
+	sibling, err := t.allocate(diskPageLeaf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	half := len(segments) / 2
+	p, s := t.page(id), t.page(sibling)
+	p.setSegments(segments[:half])
+	s.setSegments(segments[half:])
+
+	s.setPrev(id)
+	s.setNext(p.next())
+
+	if p.next() != 0 {
+		t.page(p.next()).setPrev(sibling)
+	}
+
+	p.setNext(sibling)
+
+	return &diskChild{segments[half].left, sibling}, nil
+}
+
+// Validate checks that every page is within its capacity, that the keys of
+// internal pages bound the lefts of their children's segments, that all leaves are at the same
+// depth and that the leaf links visit every segment in order.
+func (t *DiskTree) Validate() error {
+	leaves := make([]uint64, 0)
+	visited := make(map[uint64]bool)
+
+	//low and high are inclusive, so that a segment can start at MaxInt64
+	var validatePage func(id uint64, low, high int64, depth int) (int, error)
+
+	validatePage = func(id uint64, low, high int64, depth int) (int, error) {
+		if id == 0 || id >= t.pageCount() {
+			return 0, fmt.Errorf("%w: page %d out of range", ErrCorruptDiskTree, id)
+		}
+
+		if visited[id] {
+			return 0, fmt.Errorf("%w: page %d", ErrCircularReference, id)
+		}
+
+		visited[id] = true
+
+		p := t.page(id)
+
+		switch p.kind() {
+		case diskPageLeaf:
+			if p.count() > t.leafCapacity() {
+				return 0, fmt.Errorf("%w: leaf %d holds %d segments", ErrCorruptDiskTree, id, p.count())
+			}
+
+			for _, r := range p.segments() {
+				if r.left < low || r.left > high {
+					return 0, &RangeError{Op: "validate", Ranges: []indexRange{r}, Err: ErrOutOfOrder}
+				}
+			}
+
+			leaves = append(leaves, id)
+			return depth, nil
+
+		case diskPageInternal:
+			children := p.children()
+
+			if len(children) == 0 || len(children) > t.internalCapacity() {
+				return 0, fmt.Errorf("%w: internal page %d holds %d children", ErrCorruptDiskTree, id, len(children))
+			}
+
+			leafDepth := -1
+
+			for i, c := range children {
+				childLow, childHigh := c.key, high
+
+				if i == 0 {
+					childLow = low
+				}
+
+				if i+1 < len(children) {
+					childHigh = children[i+1].key - 1
+				}
+
+				if childLow < low || childHigh < childLow || childHigh > high || (i > 0 && c.key <= children[i-1].key) {
+					return 0, fmt.Errorf("%w: internal page %d keys out of order", ErrCorruptDiskTree, id)
+				}
+
+				childDepth, err := validatePage(c.child, childLow, childHigh, depth+1)
+
+				if err != nil {
+					return 0, err
+				}
+
+				if leafDepth != -1 && childDepth != leafDepth {
+					return 0, fmt.Errorf("%w: leaves of page %d at different depths", ErrCorruptDiskTree, id)
+				}
+
+				leafDepth = childDepth
+			}
+
+			return leafDepth, nil
+
+		default:
+			return 0, fmt.Errorf("%w: page %d has kind %d", ErrCorruptDiskTree, id, p.kind())
+		}
+	}
+
+	if _, err := validatePage(t.root(), math.MinInt64, math.MaxInt64, 0); err != nil {
+		return err
+	}
+
+	checker := segmentChecker{op: "validate"}
+	prev := uint64(0)
+
+	for i, id := range leaves {
+		p := t.page(id)
+
+		if p.prev() != prev || (i+1 < len(leaves) && p.next() != leaves[i+1]) || (i+1 == len(leaves) && p.next() != 0) {
+			return fmt.Errorf("%w: leaf %d", ErrBrokenLink, id)
+		}
+
+		for _, r := range p.segments() {
+			if err := checker.check(r); err != nil {
+				return err
+			}
+		}
+
+		prev = id
+	}
+
+	return nil
+}
+
+// writeDOT draws every page with its entries, the links from internal pages
+// to their children and the next links between leaves.
+func (t *DiskTree) writeDOT(w io.Writer) error {
+	g := makeDOTGraph("disk_tree")
+
+	var writePage func(id uint64) string
+
+	writePage = func(id uint64) string {
+		name, _ := g.id(id)
+		p := t.page(id)
+
+		if p.kind() != diskPageInternal {
+			fields := []string{fmt.Sprintf("leaf %d", id)}
+
+			for _, r := range p.segments() {
+				fields = append(fields, dotRangeLabel(r))
+			}
+
+			g.node(name, fields...)
+
+			return name
+		}
+
+		fields := []string{fmt.Sprintf("page %d", id)}
+		children := p.children()
+
+		for i, c := range children {
+			if i == 0 {
+				fields = append(fields, "…")
+			} else {
+				fields = append(fields, fmt.Sprintf("%d…", c.key))
+			}
+		}
+
+		g.node(name, fields...)
+
+		for _, c := range children {
+			g.edge(name, writePage(c.child), "")
+		}
+
+		return name
+	}
+
+	writePage(t.root())
+
+	for id := diskFirstLeaf; t.page(id).next() != 0; id = t.page(id).next() {
+		from, _ := g.id(id)
+		to, seen := g.id(t.page(id).next())
+		g.edge(from, to, "style=dashed, label=next, constraint=false")
+
+		if !seen {
+			break
+		}
+	}
+
+	return g.writeTo(w)
+}
@@ -0,0 +1,181 @@
+package indexset
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// diskTreeTestPageSize fits 4 segments in a leaf and 6 children in an
+// internal page, so a few dozen segments already build a three level tree.
+const diskTreeTestPageSize = 128
+
+func openDiskTreeTest(t *testing.T, path string) *DiskTree {
+	tree, err := openDiskTree(path, diskTreeTestPageSize)
+	assert.Nil(t, err)
+
+	t.Cleanup(
+		func() {
+			if tree.data != nil {
+				tree.Close()
+			}
+		},
+	)
+
+	return tree
+}
+
+func TestDiskTreeAgainstPersistentTree(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	tree := openDiskTreeTest(t, filepath.Join(t.TempDir(), "tree"))
+	disk := &Set{Implementation: tree}
+	expected := &Set{Implementation: NewPersistentTree()}
+
+	for i := 0; i < 500; i++ {
+		left := random.Int63n(1000)
+		r := indexRange{left, left + random.Int63n(20), random.Int63n(10) - 3}
+
+		assert.Nil(t, disk.Add(r))
+		assert.Nil(t, expected.Add(r))
+
+		if err := tree.Validate(); err != nil {
+			t.Fatalf("after adding %s: %s", r, err)
+		}
+	}
+
+	assert.Equal(t, collectIndexRanges(t, expected), collectIndexRanges(t, disk))
+	assert.Greater(t, tree.pageCount(), uint64(100))
+
+	for _, window := range []indexRange{{0, 0, 0}, {17, 90, 0}, {500, 500, 0}, {990, 2000, 0}} {
+		assert.Equal(t, expected.FindOverlapping(window), toTreeMembers(disk.FindOverlapping(window)), window.String())
+	}
+}
+
+func toTreeMembers(members []Member) []Member {
+	converted := make([]Member, len(members))
+
+	for i, m := range members {
+		converted[i] = treeMember(m.IndexRange())
+	}
+
+	return converted
+}
+
+func TestDiskTreeSeekPastEmptyLeaves(t *testing.T) {
+	tree := openDiskTreeTest(t, filepath.Join(t.TempDir(), "tree"))
+	s := &Set{Implementation: tree}
+
+	assert.Nil(t, s.Add(indexRange{0, 100, 1}))
+
+	for i := int64(200); i < 240; i++ {
+		assert.Nil(t, s.Add(indexRange{i, i, 1}))
+	}
+
+	//empty every leaf after the first so seek has to walk back over them
+	for i := int64(200); i < 240; i++ {
+		assert.Nil(t, tree.Replace(diskMember{i, i, 1}))
+	}
+
+	assert.Nil(t, tree.Validate())
+	assert.Equal(t, []Member{diskMember{0, 100, 1}}, tree.FindOverlapping(indexRange{50, 300, 0}))
+	assert.Equal(t, int64(1), s.At(100))
+	assert.Equal(t, int64(0), s.At(230))
+}
+
+func TestDiskTreeReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree")
+	tree := openDiskTreeTest(t, path)
+	s := &Set{Implementation: tree}
+
+	for i := int64(0); i < 100; i++ {
+		assert.Nil(t, s.Add(indexRange{i * 2, i*2 + 2, 1}))
+	}
+
+	expected := collectIndexRanges(t, s)
+	assert.Nil(t, tree.Close())
+
+	//the page size is read back from the file
+	tree, err := OpenDiskTree(path)
+	assert.Nil(t, err)
+	defer tree.Close()
+
+	assert.Equal(t, diskTreeTestPageSize, tree.pageSize)
+	assert.Nil(t, tree.Validate())
+	assert.Equal(t, expected, collectIndexRanges(t, &Set{Implementation: tree}))
+}
+
+func TestDiskTreeValidateMaxInt64(t *testing.T) {
+	tree := openDiskTreeTest(t, filepath.Join(t.TempDir(), "tree"))
+	s := &Set{Implementation: tree}
+
+	for i := int64(0); i < 30; i++ {
+		assert.Nil(t, s.Add(indexRange{i, i, 1}))
+	}
+
+	assert.Nil(t, s.Add(indexRange{math.MaxInt64, math.MaxInt64, 1}))
+	assert.Nil(t, tree.Validate())
+}
+
+func TestDiskTreeCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree")
+	assert.Nil(t, os.WriteFile(path, []byte("not a tree"), 0o644))
+
+	_, err := OpenDiskTree(path)
+	assert.True(t, errors.Is(err, ErrCorruptDiskTree))
+
+	tree := openDiskTreeTest(t, filepath.Join(t.TempDir(), "tree"))
+	s := &Set{Implementation: tree}
+
+	for i := int64(0); i < 10; i++ {
+		assert.Nil(t, s.Add(indexRange{i, i, 1}))
+	}
+
+	//swap two segments of the first leaf
+	first := tree.page(diskFirstLeaf)
+	a, b := first.segment(0), first.segment(1)
+	first.setSegment(0, b)
+	first.setSegment(1, a)
+
+	assert.True(t, errors.Is(tree.Validate(), ErrCorrupt))
+	assert.True(t, errors.Is(s.Do(func(Member) bool { return false }), ErrOutOfOrder))
+}
+
+func TestDiskTreeReplaceNotFound(t *testing.T) {
+	tree := openDiskTreeTest(t, filepath.Join(t.TempDir(), "tree"))
+
+	_, err := tree.AddOrFindOverlapping(indexRange{1, 5, 1})
+	assert.Nil(t, err)
+
+	err = tree.Replace(diskMember{1, 5, 2})
+	assert.True(t, errors.Is(err, ErrMemberNotFound))
+}
+
+func TestDiskTreeWriteDOT(t *testing.T) {
+	s := &Set{Implementation: openDiskTreeTest(t, filepath.Join(t.TempDir(), "tree"))}
+
+	for i := int64(0); i < 5; i++ {
+		assert.Nil(t, s.Add(indexRange{i * 10, i*10 + 1, i + 1}))
+	}
+
+	out := &bytes.Buffer{}
+	assert.Nil(t, s.WriteDOT(out))
+	assert.Equal(
+		t,
+		"digraph disk_tree {\n"+
+			"\tnode [shape=record];\n"+
+			"\tn0 [label=\"page 3|…|20…\"];\n"+
+			"\tn1 [label=\"leaf 1|0_1|w=1|10_11|w=2\"];\n"+
+			"\tn0 -> n1;\n"+
+			"\tn2 [label=\"leaf 2|20_21|w=3|30_31|w=4|40_41|w=5\"];\n"+
+			"\tn0 -> n2;\n"+
+			"\tn1 -> n2 [style=dashed, label=next, constraint=false];\n"+
+			"}\n",
+		out.String(),
+	)
+}
@@ -0,0 +1,155 @@
+package indexset
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type dotWriter interface {
+	writeDOT(w io.Writer) error
+}
+
+// WriteDOT writes the internal structure of the Implementation as a
+// Graphviz digraph. Nodes are drawn as stored, without validating them, so
+// the output can be used to look at a corrupt set. The Implementation must
+// support DOT output.
+func (s *Set) WriteDOT(w io.Writer) error {
+	dotWriter, ok := s.Implementation.(dotWriter)
+
+	if !ok {
+		return fmt.Errorf("%w: dot: %T", ErrNotSupported, s.Implementation)
+	}
+
+	return dotWriter.writeDOT(w)
+}
+
+// dotGraph collects the lines of a digraph and numbers the nodes in the
+// order they are first seen.
+type dotGraph struct {
+	sb  strings.Builder
+	ids map[any]int
+}
+
+func makeDOTGraph(name string) *dotGraph {
+	g := &dotGraph{ids: make(map[any]int)}
+	fmt.Fprintf(&g.sb, "digraph %s {\n", name)
+	g.sb.WriteString("\tnode [shape=record];\n")
+	return g
+}
+
+// id returns the name of n in the graph and whether it was seen before.
+func (g *dotGraph) id(n any) (string, bool) {
+	id, seen := g.ids[n]
+
+	if !seen {
+		id = len(g.ids)
+		g.ids[n] = id
+	}
+
+	return fmt.Sprintf("n%d", id), seen
+}
+
+func (g *dotGraph) node(id string, fields ...string) {
+	fmt.Fprintf(&g.sb, "\t%s [label=\"%s\"];\n", id, strings.Join(fields, "|"))
+}
+
+func (g *dotGraph) edge(from, to string, attributes string) {
+	if attributes == "" {
+		fmt.Fprintf(&g.sb, "\t%s -> %s;\n", from, to)
+		return
+	}
+
+	fmt.Fprintf(&g.sb, "\t%s -> %s [%s];\n", from, to, attributes)
+}
+
+func (g *dotGraph) writeTo(w io.Writer) error {
+	g.sb.WriteString("}\n")
+	_, err := io.WriteString(w, g.sb.String())
+	return err
+}
+
+func dotRangeLabel(r indexRange) string {
+	return fmt.Sprintf("%d_%d|w=%d", r.left, r.right, r.weight)
+}
+
+// writeDOT draws every node with its next and prev links. A cycle in the
+// next links is drawn once instead of looping.
+func (l *linkedList) writeDOT(w io.Writer) error {
+	g := makeDOTGraph("linked_list")
+
+	if l.head != nil {
+		head, _ := g.id(l.head)
+		g.sb.WriteString("\thead [shape=plaintext];\n")
+		g.edge("head", head, "")
+	}
+
+	for currentNode := l.head; currentNode != nil; currentNode = currentNode.next {
+		id, _ := g.id(currentNode)
+		g.node(id, dotRangeLabel(currentNode.indexRange))
+
+		if currentNode.prev != nil {
+			prev, _ := g.id(currentNode.prev)
+			g.edge(id, prev, "style=dashed, label=prev")
+		}
+
+		if currentNode.next == nil {
+			break
+		}
+
+		next, seen := g.id(currentNode.next)
+		g.edge(id, next, "label=next")
+
+		if seen {
+			break
+		}
+	}
+
+	return g.writeTo(w)
+}
+
+func (t *persistentTree) writeDOT(w io.Writer) error {
+	return t.root.writeDOT(w)
+}
+
+func (s *treeSnapshot) writeDOT(w io.Writer) error {
+	return s.root.writeDOT(w)
+}
+
+// writeDOT draws every node with its cached height and max weight and its
+// balance, the height of before minus the height of after.
+func (n *treeNode) writeDOT(w io.Writer) error {
+	g := makeDOTGraph("persistent_tree")
+
+	var writeNode func(n *treeNode) string
+
+	writeNode = func(n *treeNode) string {
+		id, seen := g.id(n)
+
+		if seen {
+			return id
+		}
+
+		g.node(
+			id,
+			dotRangeLabel(n.indexRange),
+			fmt.Sprintf("h=%d b=%d max=%d", n.height, n.balance(), n.maxWeight),
+		)
+
+		if n.before != nil {
+			g.edge(id, writeNode(n.before), "label=before")
+		}
+
+		if n.after != nil {
+			g.edge(id, writeNode(n.after), "label=after")
+		}
+
+		return id
+	}
+
+	if n != nil {
+		writeNode(n)
+	}
+
+	return g.writeTo(w)
+}
@@ -0,0 +1,98 @@
+package indexset
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteDOT(t *testing.T) {
+	testCases := map[string]string{
+		"linked_list": "digraph linked_list {\n" +
+			"\tnode [shape=record];\n" +
+			"\thead [shape=plaintext];\n" +
+			"\thead -> n0;\n" +
+			"\tn0 [label=\"0_1|w=2\"];\n" +
+			"\tn0 -> n1 [label=next];\n" +
+			"\tn1 [label=\"2_3|w=4\"];\n" +
+			"\tn1 -> n0 [style=dashed, label=prev];\n" +
+			"\tn1 -> n2 [label=next];\n" +
+			"\tn2 [label=\"4_5|w=2\"];\n" +
+			"\tn2 -> n1 [style=dashed, label=prev];\n" +
+			"\tn2 -> n3 [label=next];\n" +
+			"\tn3 [label=\"8_9|w=1\"];\n" +
+			"\tn3 -> n2 [style=dashed, label=prev];\n" +
+			"}\n",
+		"persistent_tree": "digraph persistent_tree {\n" +
+			"\tnode [shape=record];\n" +
+			"\tn0 [label=\"2_3|w=4|h=3 b=-1 max=4\"];\n" +
+			"\tn1 [label=\"0_1|w=2|h=1 b=0 max=2\"];\n" +
+			"\tn0 -> n1 [label=before];\n" +
+			"\tn2 [label=\"4_5|w=2|h=2 b=-1 max=2\"];\n" +
+			"\tn3 [label=\"8_9|w=1|h=1 b=0 max=1\"];\n" +
+			"\tn2 -> n3 [label=after];\n" +
+			"\tn0 -> n2 [label=after];\n" +
+			"}\n",
+		"disk_tree": "digraph disk_tree {\n" +
+			"\tnode [shape=record];\n" +
+			"\tn0 [label=\"leaf 1|0_1|w=2|2_3|w=4|4_5|w=2|8_9|w=1\"];\n" +
+			"}\n",
+	}
+
+	for name, newImplementation := range implementationsToTest(t) {
+		t.Run(name, func(t *testing.T) {
+			s := renderTestSet(t, newImplementation)
+			out := &bytes.Buffer{}
+
+			assert.Nil(t, s.WriteDOT(out))
+			assert.Equal(t, testCases[name], out.String())
+		})
+	}
+}
+
+func TestWriteDOTSnapshot(t *testing.T) {
+	s := renderTestSet(t, NewPersistentTree)
+	snapshot, err := s.Snapshot()
+	assert.Nil(t, err)
+
+	expected, actual := &bytes.Buffer{}, &bytes.Buffer{}
+	assert.Nil(t, s.WriteDOT(expected))
+	assert.Nil(t, snapshot.WriteDOT(actual))
+	assert.Equal(t, expected.String(), actual.String())
+}
+
+func TestWriteDOTEmpty(t *testing.T) {
+	for name, newImplementation := range implementationsToTest(t) {
+		t.Run(name, func(t *testing.T) {
+			s := &Set{Implementation: newImplementation()}
+			out := &bytes.Buffer{}
+
+			expected := "digraph " + name + " {\n\tnode [shape=record];\n}\n"
+
+			//a disk tree always has a root leaf
+			if name == "disk_tree" {
+				expected = "digraph disk_tree {\n\tnode [shape=record];\n\tn0 [label=\"leaf 1\"];\n}\n"
+			}
+
+			assert.Nil(t, s.WriteDOT(out))
+			assert.Equal(t, expected, out.String())
+		})
+	}
+}
+
+func TestWriteDOTCycle(t *testing.T) {
+	l := makeLinkedList(indexRange{0, 1, 1}, indexRange{2, 3, 1})
+	l.head.next.next = l.head
+
+	out := &bytes.Buffer{}
+	assert.Nil(t, (&Set{Implementation: l}).WriteDOT(out))
+	assert.Contains(t, out.String(), "\tn1 -> n0 [label=next];\n")
+}
+
+func TestWriteDOTNotSupported(t *testing.T) {
+	s := &Set{Implementation: struct{ Implementation }{NewLinkedList()}}
+	err := s.WriteDOT(&bytes.Buffer{})
+	assert.True(t, errors.Is(err, ErrNotSupported))
+}
@@ -0,0 +1,474 @@
+//go:generate stringer -type=SyncMode -trimprefix=SyncMode
+
+package indexset
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SyncMode selects when a DurableSet calls fsync on its log.
+type SyncMode int
+
+const (
+	// SyncModeAlways syncs after every record, so a nil error from Add
+	// means the change survives a crash.
+	SyncModeAlways = SyncMode(iota)
+	// SyncModeInterval syncs after a record once SyncInterval has passed
+	// since the last sync. A crash loses at most that much of the log.
+	SyncModeInterval
+	// SyncModeNever leaves syncing to Sync, Checkpoint, Close and the
+	// operating system.
+	SyncModeNever
+)
+
+const (
+	durableLogName      = "wal"
+	durableSnapshotName = "snapshot"
+
+	durableOpAdd      = byte(1)
+	durableOpSubtract = byte(2)
+
+	//seq, op, left, right, weight, crc32
+	durableRecordSize = 8 + 1 + 3*8 + 4
+)
+
+var durableSnapshotMagic = [8]byte{'I', 'X', 'S', 'E', 'T', 'S', 'S', '1'}
+
+type DurableOptions struct {
+	// NewImplementation backs the set, NewPersistentTree by default.
+	NewImplementation func() Implementation
+	Sync              SyncMode
+	SyncInterval      time.Duration
+	// SnapshotEvery checkpoints after that many records have been logged
+	// since the last checkpoint. 0 only checkpoints when Checkpoint is
+	// called.
+	SnapshotEvery int
+}
+
+// DurableSet is a Set kept in a directory as a binary snapshot plus a
+// write-ahead log of the Adds and Subtracts made since. Every record carries
+// a sequence number, and the snapshot records the last one it includes, so
+// a crash at any point during Checkpoint leaves a directory that opens to
+// the same segments. A DurableSet is not safe for concurrent use.
+type DurableSet struct {
+	set           Set
+	dir           string
+	options       DurableOptions
+	log           *os.File
+	seq           uint64
+	sinceSnapshot int
+	lastSync      time.Time
+	//err is set when the log could not be written, after which the log may
+	//hold a record the set in memory does not and every write fails
+	err error
+}
+
+// OpenDurableSet creates dir if needed and rebuilds the set from its
+// snapshot and log. A record torn by a crash at the end of the log is
+// dropped, while a damaged record followed by others fails with
+// ErrCorruptLog and leaves the log as it is.
+func OpenDurableSet(dir string, options DurableOptions) (*DurableSet, error) {
+	if options.NewImplementation == nil {
+		options.NewImplementation = NewPersistentTree
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	d := &DurableSet{
+		set:      Set{Implementation: options.NewImplementation()},
+		dir:      dir,
+		options:  options,
+		lastSync: time.Now(),
+	}
+
+	if err := d.readSnapshot(); err != nil {
+		return nil, err
+	}
+
+	log, err := os.OpenFile(filepath.Join(dir, durableLogName), os.O_RDWR|os.O_CREATE, 0o644)
+
+	if err != nil {
+		return nil, err
+	}
+
+	d.log = log
+
+	if err := d.replay(); err != nil {
+		log.Close()
+		return nil, err
+	}
+
+	if err := syncDir(dir); err != nil {
+		log.Close()
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Set returns the set in memory. Changes made through it bypass the log.
+func (d *DurableSet) Set() *Set {
+	return &d.set
+}
+
+// Add logs newRange and then adds it to the set. The Add is staged in a
+// transaction first, so that a range that cannot be added is never logged,
+// and it only becomes visible once the record has been written. If the
+// change is logged but the automatic checkpoint after it fails, the error is
+// still returned.
+func (d *DurableSet) Add(newRange indexRange) error {
+	return d.apply(durableOpAdd, newRange)
+}
+
+func (d *DurableSet) Subtract(oldRange indexRange) error {
+	return d.apply(durableOpSubtract, oldRange)
+}
+
+func (d *DurableSet) apply(op byte, r indexRange) error {
+	if d.err != nil {
+		return d.err
+	}
+
+	if _, err := MakeRange(r.left, r.right, r.weight); err != nil {
+		return err
+	}
+
+	tx := d.set.Begin()
+
+	if err := applyDurableOp(tx, op, r); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := d.append(op, r); err != nil {
+		d.err = fmt.Errorf("write-ahead log: %w", err)
+
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("%w; rolling back failed: %s", d.err, rollbackErr)
+		}
+
+		return d.err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if d.options.SnapshotEvery > 0 && d.sinceSnapshot >= d.options.SnapshotEvery {
+		return d.Checkpoint()
+	}
+
+	return nil
+}
+
+// durableTarget is a Set, or a Transaction on one.
+type durableTarget interface {
+	Add(indexRange) error
+	Subtract(indexRange) error
+}
+
+func applyDurableOp(s durableTarget, op byte, r indexRange) error {
+	if op == durableOpSubtract {
+		return s.Subtract(r)
+	}
+
+	return s.Add(r)
+}
+
+func (d *DurableSet) append(op byte, r indexRange) error {
+	record := encodeDurableRecord(d.seq+1, op, r)
+
+	if _, err := d.log.Write(record[:]); err != nil {
+		return err
+	}
+
+	d.seq++
+	d.sinceSnapshot++
+
+	switch d.options.Sync {
+	case SyncModeAlways:
+		return d.Sync()
+
+	case SyncModeInterval:
+		if time.Since(d.lastSync) >= d.options.SyncInterval {
+			return d.Sync()
+		}
+	}
+
+	return nil
+}
+
+// Sync flushes the log to stable storage.
+func (d *DurableSet) Sync() error {
+	if err := d.log.Sync(); err != nil {
+		return err
+	}
+
+	d.lastSync = time.Now()
+	return nil
+}
+
+// Checkpoint writes a snapshot of the set and empties the log.
+func (d *DurableSet) Checkpoint() error {
+	if d.err != nil {
+		return d.err
+	}
+
+	if err := d.writeSnapshot(); err != nil {
+		return err
+	}
+
+	//the snapshot covers every record, so a crash before the truncation only
+	//leaves records that replay skips
+	if err := d.log.Truncate(0); err != nil {
+		d.err = fmt.Errorf("write-ahead log: %w", err)
+		return d.err
+	}
+
+	if _, err := d.log.Seek(0, io.SeekStart); err != nil {
+		d.err = fmt.Errorf("write-ahead log: %w", err)
+		return d.err
+	}
+
+	d.sinceSnapshot = 0
+
+	return d.Sync()
+}
+
+// Close syncs and closes the log. The set must not be used afterwards.
+func (d *DurableSet) Close() error {
+	err := d.log.Sync()
+
+	if closeErr := d.log.Close(); err == nil {
+		err = closeErr
+	}
+
+	d.err = os.ErrClosed
+
+	return err
+}
+
+func encodeDurableRecord(seq uint64, op byte, r indexRange) (record [durableRecordSize]byte) {
+	binary.LittleEndian.PutUint64(record[0:], seq)
+	record[8] = op
+	binary.LittleEndian.PutUint64(record[9:], uint64(r.left))
+	binary.LittleEndian.PutUint64(record[17:], uint64(r.right))
+	binary.LittleEndian.PutUint64(record[25:], uint64(r.weight))
+	binary.LittleEndian.PutUint32(record[33:], crc32.ChecksumIEEE(record[:33]))
+
+	return record
+}
+
+func decodeDurableRecord(record []byte) (seq uint64, op byte, r indexRange, ok bool) {
+	if binary.LittleEndian.Uint32(record[33:]) != crc32.ChecksumIEEE(record[:33]) {
+		return 0, 0, r, false
+	}
+
+	seq = binary.LittleEndian.Uint64(record[0:])
+	op = record[8]
+	r.left = int64(binary.LittleEndian.Uint64(record[9:]))
+	r.right = int64(binary.LittleEndian.Uint64(record[17:]))
+	r.weight = int64(binary.LittleEndian.Uint64(record[25:]))
+
+	return seq, op, r, true
+}
+
+// replay applies the records logged after the snapshot and truncates the log
+// after the last intact record. Only the final record may be torn.
+func (d *DurableSet) replay() error {
+	reader := bufio.NewReader(d.log)
+	record := make([]byte, durableRecordSize)
+	valid := int64(0)
+
+	for {
+		if _, err := io.ReadFull(reader, record); err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		seq, op, r, ok := decodeDurableRecord(record)
+
+		//a crash can only tear the last record, so a checksum mismatch
+		//anywhere else is corruption
+		if !ok {
+			if _, err := reader.Peek(1); err != io.EOF {
+				if err != nil {
+					return err
+				}
+
+				return fmt.Errorf("%w: record after %d has a bad checksum", ErrCorruptLog, d.seq)
+			}
+
+			break
+		}
+
+		if op != durableOpAdd && op != durableOpSubtract {
+			return fmt.Errorf("%w: record %d has unknown op %d", ErrCorruptLog, seq, op)
+		}
+
+		valid += durableRecordSize
+
+		if seq <= d.seq {
+			continue
+		}
+
+		if seq != d.seq+1 {
+			return fmt.Errorf("%w: record %d follows %d", ErrCorruptLog, seq, d.seq)
+		}
+
+		if err := applyDurableOp(&d.set, op, r); err != nil {
+			return fmt.Errorf("replaying record %d: %w", seq, err)
+		}
+
+		d.seq = seq
+		d.sinceSnapshot++
+	}
+
+	if err := d.log.Truncate(valid); err != nil {
+		return err
+	}
+
+	_, err := d.log.Seek(valid, io.SeekStart)
+	return err
+}
+
+// writeSnapshot writes the magic, the last sequence number, the segment
+// count, every segment and a crc32 of all of the above to a temporary file
+// and renames it over the previous snapshot.
+func (d *DurableSet) writeSnapshot() error {
+	path := filepath.Join(d.dir, durableSnapshotName)
+	file, err := os.Create(path + ".tmp")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(path + ".tmp")
+
+	checksum := crc32.NewIEEE()
+	writer := bufio.NewWriter(io.MultiWriter(file, checksum))
+	segments := make([]indexRange, 0)
+
+	err = d.set.Do(
+		func(m Member) bool {
+			segments = append(segments, m.IndexRange())
+			return false
+		},
+	)
+
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	writer.Write(durableSnapshotMagic[:])
+	binary.Write(writer, binary.LittleEndian, d.seq)
+	binary.Write(writer, binary.LittleEndian, uint64(len(segments)))
+
+	for _, r := range segments {
+		binary.Write(writer, binary.LittleEndian, [3]int64{r.left, r.right, r.weight})
+	}
+
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := binary.Write(file, binary.LittleEndian, checksum.Sum32()); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+
+	return syncDir(d.dir)
+}
+
+func (d *DurableSet) readSnapshot() error {
+	contents, err := os.ReadFile(filepath.Join(d.dir, durableSnapshotName))
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	const headerSize = 8 + 8 + 8
+
+	if len(contents) < headerSize+4 || !bytes.Equal(contents[:8], durableSnapshotMagic[:]) {
+		return fmt.Errorf("%w: bad header", ErrCorruptSnapshot)
+	}
+
+	body, checksum := contents[:len(contents)-4], binary.LittleEndian.Uint32(contents[len(contents)-4:])
+
+	if crc32.ChecksumIEEE(body) != checksum {
+		return fmt.Errorf("%w: checksum mismatch", ErrCorruptSnapshot)
+	}
+
+	seq := binary.LittleEndian.Uint64(body[8:])
+	count := binary.LittleEndian.Uint64(body[16:])
+	body = body[headerSize:]
+
+	if uint64(len(body)) != count*24 {
+		return fmt.Errorf("%w: expected %d segments in %d bytes", ErrCorruptSnapshot, count, len(body))
+	}
+
+	checker := segmentChecker{op: "snapshot"}
+
+	for ; len(body) > 0; body = body[24:] {
+		r := indexRange{
+			left:   int64(binary.LittleEndian.Uint64(body[0:])),
+			right:  int64(binary.LittleEndian.Uint64(body[8:])),
+			weight: int64(binary.LittleEndian.Uint64(body[16:])),
+		}
+
+		if err := checker.check(r); err != nil {
+			return err
+		}
+
+		if err := d.set.Add(r); err != nil {
+			return err
+		}
+	}
+
+	d.seq = seq
+
+	return nil
+}
+
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	return file.Sync()
+}
@@ -0,0 +1,233 @@
+package indexset
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type durableOp struct {
+	subtract bool
+	r        indexRange
+}
+
+func durableTestOps() []durableOp {
+	return []durableOp{
+		{r: indexRange{1, 5, 2}},
+		{r: indexRange{4, 9, 3}},
+		{subtract: true, r: indexRange{1, 2, 2}},
+		{r: indexRange{20, 20, 1}},
+	}
+}
+
+var durableTestExpected = []indexRange{{3, 3, 2}, {4, 5, 5}, {6, 9, 3}, {20, 20, 1}}
+
+func applyDurableTestOps(t *testing.T, d *DurableSet, ops []durableOp) {
+	for _, op := range ops {
+		if op.subtract {
+			assert.Nil(t, d.Subtract(op.r))
+		} else {
+			assert.Nil(t, d.Add(op.r))
+		}
+	}
+}
+
+func openDurableTestSet(t *testing.T, dir string, options DurableOptions) *DurableSet {
+	d, err := OpenDurableSet(dir, options)
+	assert.Nil(t, err)
+	return d
+}
+
+func TestDurableSetReopen(t *testing.T) {
+	for name, newImplementation := range implementationsToTest(t) {
+		for _, sync := range []SyncMode{SyncModeAlways, SyncModeInterval, SyncModeNever} {
+			t.Run(name+"/"+sync.String(), func(t *testing.T) {
+				dir := t.TempDir()
+				options := DurableOptions{NewImplementation: newImplementation, Sync: sync}
+
+				d := openDurableTestSet(t, dir, options)
+				applyDurableTestOps(t, d, durableTestOps())
+				assert.Equal(t, durableTestExpected, collectIndexRanges(t, d.Set()))
+				assert.Nil(t, d.Close())
+
+				d = openDurableTestSet(t, dir, options)
+				defer d.Close()
+				assert.Equal(t, durableTestExpected, collectIndexRanges(t, d.Set()))
+			})
+		}
+	}
+}
+
+func TestDurableSetCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	ops := durableTestOps()
+
+	d := openDurableTestSet(t, dir, DurableOptions{})
+	applyDurableTestOps(t, d, ops[:2])
+	assert.Nil(t, d.Checkpoint())
+	applyDurableTestOps(t, d, ops[2:])
+	assert.Nil(t, d.Close())
+
+	info, err := os.Stat(filepath.Join(dir, durableLogName))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2*durableRecordSize), info.Size())
+
+	d = openDurableTestSet(t, dir, DurableOptions{})
+	defer d.Close()
+	assert.Equal(t, durableTestExpected, collectIndexRanges(t, d.Set()))
+}
+
+func TestDurableSetSnapshotEvery(t *testing.T) {
+	dir := t.TempDir()
+
+	d := openDurableTestSet(t, dir, DurableOptions{SnapshotEvery: 3})
+	applyDurableTestOps(t, d, durableTestOps())
+	assert.Nil(t, d.Close())
+
+	_, err := os.Stat(filepath.Join(dir, durableSnapshotName))
+	assert.Nil(t, err)
+
+	info, err := os.Stat(filepath.Join(dir, durableLogName))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(durableRecordSize), info.Size())
+
+	d = openDurableTestSet(t, dir, DurableOptions{})
+	defer d.Close()
+	assert.Equal(t, durableTestExpected, collectIndexRanges(t, d.Set()))
+}
+
+func TestDurableSetTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	ops := durableTestOps()
+
+	d := openDurableTestSet(t, dir, DurableOptions{})
+	applyDurableTestOps(t, d, ops)
+	assert.Nil(t, d.Close())
+
+	//cut the last record in half, as a crash during the write would
+	path := filepath.Join(dir, durableLogName)
+	assert.Nil(t, os.Truncate(path, int64(len(ops))*durableRecordSize-durableRecordSize/2))
+
+	d = openDurableTestSet(t, dir, DurableOptions{})
+	assert.Equal(t, []indexRange{{3, 3, 2}, {4, 5, 5}, {6, 9, 3}}, collectIndexRanges(t, d.Set()))
+
+	//the torn record is dropped so new records follow the last intact one
+	assert.Nil(t, d.Add(indexRange{30, 30, 1}))
+	assert.Nil(t, d.Close())
+
+	d = openDurableTestSet(t, dir, DurableOptions{})
+	defer d.Close()
+	assert.Equal(t, []indexRange{{3, 3, 2}, {4, 5, 5}, {6, 9, 3}, {30, 30, 1}}, collectIndexRanges(t, d.Set()))
+}
+
+func TestDurableSetCorruptRecord(t *testing.T) {
+	dir := t.TempDir()
+	ops := durableTestOps()
+
+	d := openDurableTestSet(t, dir, DurableOptions{})
+	applyDurableTestOps(t, d, ops)
+	assert.Nil(t, d.Close())
+
+	//flip a byte in the second record, which is followed by intact ones
+	path := filepath.Join(dir, durableLogName)
+	log, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	log[durableRecordSize+10] ^= 0xff
+	assert.Nil(t, os.WriteFile(path, log, 0o644))
+
+	_, err = OpenDurableSet(dir, DurableOptions{})
+	assert.True(t, errors.Is(err, ErrCorruptLog), "%v", err)
+
+	after, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, log, after)
+}
+
+func TestDurableSetCrashBeforeTruncation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, durableLogName)
+
+	d := openDurableTestSet(t, dir, DurableOptions{})
+	applyDurableTestOps(t, d, durableTestOps())
+
+	log, err := os.ReadFile(path)
+	assert.Nil(t, err)
+
+	assert.Nil(t, d.Checkpoint())
+	assert.Nil(t, d.Close())
+
+	//put back the records the snapshot already includes
+	assert.Nil(t, os.WriteFile(path, log, 0o644))
+
+	d = openDurableTestSet(t, dir, DurableOptions{})
+	defer d.Close()
+	assert.Equal(t, durableTestExpected, collectIndexRanges(t, d.Set()))
+}
+
+func TestDurableSetCorruptSnapshot(t *testing.T) {
+	dir := t.TempDir()
+
+	d := openDurableTestSet(t, dir, DurableOptions{})
+	applyDurableTestOps(t, d, durableTestOps())
+	assert.Nil(t, d.Checkpoint())
+	assert.Nil(t, d.Close())
+
+	path := filepath.Join(dir, durableSnapshotName)
+	contents, err := os.ReadFile(path)
+	assert.Nil(t, err)
+
+	contents[len(contents)/2] ^= 0xff
+	assert.Nil(t, os.WriteFile(path, contents, 0o644))
+
+	_, err = OpenDurableSet(dir, DurableOptions{})
+	assert.True(t, errors.Is(err, ErrCorruptSnapshot))
+	assert.True(t, errors.Is(err, ErrCorrupt))
+}
+
+func TestDurableSetSequenceGap(t *testing.T) {
+	dir := t.TempDir()
+	first := encodeDurableRecord(1, durableOpAdd, indexRange{1, 2, 1})
+	third := encodeDurableRecord(3, durableOpAdd, indexRange{3, 4, 1})
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, durableLogName), append(first[:], third[:]...), 0o644))
+
+	_, err := OpenDurableSet(dir, DurableOptions{})
+	assert.True(t, errors.Is(err, ErrCorruptLog))
+}
+
+func TestDurableSetErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	d := openDurableTestSet(t, dir, DurableOptions{})
+	assert.True(t, errors.Is(d.Add(indexRange{5, 1, 1}), ErrInvalidRange))
+	assert.Nil(t, d.Add(indexRange{1, 1, 1}))
+	assert.Nil(t, d.Close())
+
+	assert.True(t, errors.Is(d.Add(indexRange{1, 1, 1}), os.ErrClosed))
+
+	//the invalid range was not logged
+	info, err := os.Stat(filepath.Join(dir, durableLogName))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(durableRecordSize), info.Size())
+}
+
+func TestDurableSetLogFailure(t *testing.T) {
+	dir := t.TempDir()
+
+	d := openDurableTestSet(t, dir, DurableOptions{})
+	assert.Nil(t, d.Add(indexRange{1, 5, 1}))
+
+	//a range that cannot be added is not logged either
+	assert.True(t, errors.Is(d.Add(indexRange{1, 1, math.MaxInt64}), ErrWeightOverflow))
+
+	assert.Nil(t, d.log.Close())
+	assert.NotNil(t, d.Add(indexRange{3, 8, 1}))
+	assert.Equal(t, []indexRange{{1, 5, 1}}, collectIndexRanges(t, d.Set()))
+
+	reopened := openDurableTestSet(t, dir, DurableOptions{})
+	defer reopened.Close()
+	assert.Equal(t, []indexRange{{1, 5, 1}}, collectIndexRanges(t, reopened.Set()))
+}
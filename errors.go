@@ -0,0 +1,63 @@
+package indexset
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidRange is wrapped by every error caused by a malformed range
+	// passed in by the caller.
+	ErrInvalidRange        = errors.New("invalid range")
+	ErrLeftLargerThanRight = fmt.Errorf("%w: left is larger than right", ErrInvalidRange)
+
+	// ErrCorrupt is wrapped by every error reporting that the segments held by
+	// an Implementation are no longer consistent.
+	ErrCorrupt           = errors.New("corrupt set")
+	ErrUnknownOverlap    = fmt.Errorf("%w: failed to combine nodes: unknown overlap", ErrCorrupt)
+	ErrImpossibleState   = fmt.Errorf("%w: impossible state", ErrCorrupt)
+	ErrOutOfOrder        = fmt.Errorf("%w: ranges out of order", ErrCorrupt)
+	ErrOverlap           = fmt.Errorf("%w: ranges overlap", ErrCorrupt)
+	ErrEmptySegment      = fmt.Errorf("%w: zero-length segment", ErrCorrupt)
+	ErrBrokenLink        = fmt.Errorf("%w: inconsistent links", ErrCorrupt)
+	ErrUnbalanced        = fmt.Errorf("%w: inconsistent tree node", ErrCorrupt)
+	ErrCircularReference = fmt.Errorf("%w: circular reference", ErrCorrupt)
+	ErrCorruptSnapshot   = fmt.Errorf("%w: snapshot", ErrCorrupt)
+	ErrCorruptLog        = fmt.Errorf("%w: write-ahead log", ErrCorrupt)
+	ErrCorruptDiskTree   = fmt.Errorf("%w: disk tree", ErrCorrupt)
+
+	ErrInvalidMember     = errors.New("member is not an instance of node")
+	ErrMemberNotFound    = errors.New("member not found")
+	ErrReadOnly          = errors.New("set is read-only")
+	ErrNotSupported      = errors.New("not supported by implementation")
+	ErrInvalidShardCount = errors.New("invalid shard count")
+	ErrWeightOverflow    = errors.New("weight overflow")
+	ErrTransactionDone   = errors.New("transaction already committed or rolled back")
+	ErrTransactionOpen   = errors.New("a nested transaction is still open")
+	ErrNothingToUndo     = errors.New("nothing to undo")
+	ErrNothingToRedo     = errors.New("nothing to redo")
+	ErrNotEmpty          = errors.New("set is not empty")
+)
+
+// RangeError records the operation and ranges that caused an error. Use
+// errors.Is on it to find out which of the sentinel errors it wraps.
+type RangeError struct {
+	Op     string
+	Ranges []indexRange
+	Err    error
+}
+
+func (e *RangeError) Error() string {
+	ranges := make([]string, len(e.Ranges))
+
+	for i, r := range e.Ranges {
+		ranges[i] = r.String()
+	}
+
+	return fmt.Sprintf("%s %s: %s", e.Op, strings.Join(ranges, ", "), e.Err)
+}
+
+func (e *RangeError) Unwrap() error {
+	return e.Err
+}
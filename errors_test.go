@@ -0,0 +1,59 @@
+package indexset
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeRangeErrors(t *testing.T) {
+	_, err := MakeRange(5, 1, 1)
+	assert.True(t, errors.Is(err, ErrLeftLargerThanRight))
+	assert.True(t, errors.Is(err, ErrInvalidRange))
+	assert.False(t, errors.Is(err, ErrCorrupt))
+
+	var rangeError *RangeError
+	assert.True(t, errors.As(err, &rangeError))
+	assert.Equal(t, []indexRange{{5, 1, 1}}, rangeError.Ranges)
+
+	r, err := MakeRange(math.MinInt64, -1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, indexRange{math.MinInt64, -1, 1}, *r)
+
+	_, err = MakeRanges([3]int64{1, 2, 1}, [3]int64{3, 2, 1})
+	assert.True(t, errors.Is(err, ErrInvalidRange))
+}
+
+func TestSplitUnknownOverlapError(t *testing.T) {
+	_, _, err := indexRange{1, 2, 1}.SplitWith(indexRange{5, 6, 1})
+	assert.True(t, errors.Is(err, ErrUnknownOverlap))
+	assert.True(t, errors.Is(err, ErrCorrupt))
+
+	var rangeError *RangeError
+	assert.True(t, errors.As(err, &rangeError))
+	assert.Equal(t, []indexRange{{1, 2, 1}, {5, 6, 1}}, rangeError.Ranges)
+}
+
+func TestReplaceMemberErrors(t *testing.T) {
+	list := &linkedList{}
+	_, err := list.AddOrFindOverlapping(indexRange{1, 2, 1})
+	assert.Nil(t, err)
+
+	err = list.Replace(treeMember{1, 2, 1}, indexRange{1, 2, 2})
+	assert.True(t, errors.Is(err, ErrInvalidMember))
+
+	tree := NewPersistentTree()
+	err = tree.Replace(treeMember{1, 2, 1}, indexRange{1, 2, 2})
+	assert.True(t, errors.Is(err, ErrMemberNotFound))
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	_, err := (&Set{Implementation: &linkedList{}}).Snapshot()
+	assert.True(t, errors.Is(err, ErrNotSupported))
+
+	snapshot, err := (&Set{Implementation: NewPersistentTree()}).Snapshot()
+	assert.Nil(t, err)
+	assert.True(t, errors.Is(snapshot.Add(indexRange{1, 2, 1}), ErrReadOnly))
+}
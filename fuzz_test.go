@@ -0,0 +1,216 @@
+package indexset
+
+import (
+	"testing"
+)
+
+const fuzzCoordinates = 96
+
+// decodeFuzzRanges turns every three bytes of data into a small range, so
+// that the fuzzer produces lots of overlaps.
+func decodeFuzzRanges(data []byte) []indexRange {
+	ranges := make([]indexRange, 0, len(data)/3)
+
+	for i := 0; i+2 < len(data); i += 3 {
+		left := int64(data[i] % 64)
+		ranges = append(
+			ranges,
+			indexRange{
+				left:   left,
+				right:  left + int64(data[i+1]%32),
+				weight: int64(data[i+2]%8) + 1,
+			},
+		)
+	}
+
+	return ranges
+}
+
+// denseOracle is the brute-force reference model: one weight per coordinate.
+type denseOracle [fuzzCoordinates]int64
+
+func (o *denseOracle) add(r indexRange) {
+	for i := r.left; i <= r.right; i++ {
+		o[i] += r.weight
+	}
+}
+
+func (o *denseOracle) max() int64 {
+	max := int64(0)
+
+	for _, weight := range o {
+		if weight > max {
+			max = weight
+		}
+	}
+
+	return max
+}
+
+func checkAgainstOracle(t *testing.T, oracle *denseOracle, set *Set) {
+	t.Helper()
+
+	if err := set.Validate(); err != nil {
+		t.Fatalf("invalid set: %s\n%s", err, set)
+	}
+
+	var actual denseOracle
+
+	err := set.Do(
+		func(m Member) bool {
+			r := m.IndexRange()
+
+			if r.right >= fuzzCoordinates {
+				t.Fatalf("segment out of bounds: %s", r)
+			}
+
+			actual.add(r)
+			return false
+		},
+	)
+
+	if err != nil {
+		t.Fatalf("iteration failed: %s", err)
+	}
+
+	if actual != *oracle {
+		t.Fatalf("segments differ from oracle\nexpected: %v\nactual:   %v\nsegments: %s", *oracle, actual, set)
+	}
+
+	if max := set.Max(); max != oracle.max() {
+		t.Fatalf("max differs from oracle: expected %d, actual %d", oracle.max(), max)
+	}
+}
+
+func addFuzzSeeds(f *testing.F) {
+	seeds := [][]indexRange{
+		{{1, 5, 1}, {2, 6, 1}, {3, 7, 1}, {4, 8, 1}, {5, 9, 1}},
+		{{1, 5, 1}, {1, 6, 1}, {1, 10, 1}, {2, 8, 1}, {5, 9, 1}},
+		{{1, 5, 1}, {5, 10, 1}, {1, 5, 1}},
+		{{4, 6, 1}, {1, 5, 1}},
+		{{3, 4, 1}, {1, 5, 1}},
+		{{1, 2, 1}, {5, 6, 1}, {0, 10, 1}},
+	}
+
+	for _, seed := range seeds {
+		data := make([]byte, 0, len(seed)*3)
+
+		for _, r := range seed {
+			data = append(data, byte(r.left), byte(r.right-r.left), byte(r.weight-1))
+		}
+
+		f.Add(data)
+	}
+}
+
+func FuzzSetAdd(f *testing.F) {
+	addFuzzSeeds(f)
+
+	f.Fuzz(
+		func(t *testing.T, data []byte) {
+			for implementationName, implementation := range implementationsToTest(t) {
+				t.Run(
+					implementationName,
+					func(t *testing.T) {
+						set := &Set{Implementation: implementation()}
+						var oracle denseOracle
+
+						for _, r := range decodeFuzzRanges(data) {
+							if err := set.Add(r); err != nil {
+								t.Fatalf("add %s: %s", r, err)
+							}
+
+							oracle.add(r)
+							checkAgainstOracle(t, &oracle, set)
+						}
+					},
+				)
+			}
+		},
+	)
+}
+
+func FuzzMerge(f *testing.F) {
+	addFuzzSeeds(f)
+
+	f.Fuzz(
+		func(t *testing.T, data []byte) {
+			for implementationName, implementation := range implementationsToTest(t) {
+				t.Run(
+					implementationName,
+					func(t *testing.T) {
+						sets := []*Set{
+							{Implementation: implementation()},
+							{Implementation: implementation()},
+							{Implementation: implementation()},
+						}
+
+						var oracle denseOracle
+
+						for i, r := range decodeFuzzRanges(data) {
+							if err := sets[i%len(sets)].Add(r); err != nil {
+								t.Fatalf("add %s: %s", r, err)
+							}
+
+							oracle.add(r)
+						}
+
+						merged, err := Merge(implementation, sets...)
+
+						if err != nil {
+							t.Fatalf("merge: %s", err)
+						}
+
+						checkAgainstOracle(t, &oracle, merged)
+					},
+				)
+			}
+		},
+	)
+}
+
+func FuzzSetSubtract(f *testing.F) {
+	addFuzzSeeds(f)
+
+	f.Fuzz(
+		func(t *testing.T, data []byte) {
+			for implementationName, implementation := range implementationsToTest(t) {
+				t.Run(
+					implementationName,
+					func(t *testing.T) {
+						set := &Set{Implementation: implementation()}
+						var oracle denseOracle
+
+						for i, r := range decodeFuzzRanges(data) {
+							if i%2 == 0 {
+								if err := set.Add(r); err != nil {
+									t.Fatalf("add %s: %s", r, err)
+								}
+
+								oracle.add(r)
+							} else {
+								if err := set.Subtract(r); err != nil {
+									t.Fatalf("subtract %s: %s", r, err)
+								}
+
+								oracle.add(indexRange{r.left, r.right, -r.weight})
+							}
+
+							checkAgainstOracle(t, &oracle, set)
+
+							set.Do(
+								func(m Member) bool {
+									if m.IndexRange().weight == 0 {
+										t.Fatalf("zero weight segment left after subtract: %s", m.IndexRange())
+									}
+
+									return false
+								},
+							)
+						}
+					},
+				)
+			}
+		},
+	)
+}
@@ -2,6 +2,7 @@ package indexset
 
 import (
 	"fmt"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -73,7 +74,10 @@ func iterationTestCases() []iterationTestCase {
 				{3, 3, 3},
 				{4, 4, 4},
 				{5, 5, 5},
-				{6, 6, 5},
+				{6, 6, 4},
+				{7, 7, 3},
+				{8, 8, 2},
+				{9, 9, 1},
 			},
 		},
 		{
@@ -89,18 +93,24 @@ func iterationTestCases() []iterationTestCase {
 				{1, 1, 3},
 				{2, 4, 4},
 				{5, 5, 5},
-				{6, 8, 2},
-				{8, 9, 2},
-				{9, 10, 1},
+				{6, 6, 4},
+				{7, 8, 3},
+				{9, 9, 2},
+				{10, 10, 1},
 			},
 		},
 	}
 }
 
+// implementationsToTest adds a DiskTree with small pages to Implementations,
+// whose files are removed when t finishes.
 func implementationsToTest(t *testing.T) map[string]func() Implementation {
-	return map[string]func() Implementation{
-		"linked_list": func() Implementation { return &linkedList{} },
+	implementations := Implementations()
+	implementations["disk_tree"] = func() Implementation {
+		return openDiskTreeTest(t, filepath.Join(t.TempDir(), "tree"))
 	}
+
+	return implementations
 }
 
 func TestIteration(t *testing.T) {
@@ -116,15 +126,7 @@ func TestIteration(t *testing.T) {
 						assert.Nil(t, err)
 					}
 
-					idx := 0
-
-					set.Implementation.Do(
-						func(m Member) bool {
-							assert.Equal(t, test.expectedIndexRanges[idx], m.IndexRange())
-							idx++
-							return false
-						},
-					)
+					assert.Equal(t, test.expectedIndexRanges, collectIndexRanges(t, set))
 				},
 			)
 		}
@@ -189,7 +191,10 @@ func TestFindOverlapping(t *testing.T) {
 					}
 
 					for _, pair := range test.pairs {
-						for i, overlappingRange := range set.FindOverlapping(pair.overlap) {
+						overlapping := set.FindOverlapping(pair.overlap)
+						assert.Len(t, overlapping, len(pair.overlapping))
+
+						for i, overlappingRange := range overlapping {
 							assert.Equal(t, pair.overlapping[i], overlappingRange.IndexRange())
 						}
 					}
@@ -274,15 +279,7 @@ func TestReplace(t *testing.T) {
 						set.Replace(nth, operation.replacement...)
 					}
 
-					idx := 0
-
-					set.Implementation.Do(
-						func(m Member) bool {
-							assert.Equal(t, test.endState[idx], m.IndexRange())
-							idx++
-							return false
-						},
-					)
+					assert.Equal(t, test.endState, collectIndexRanges(t, set))
 				},
 			)
 		}
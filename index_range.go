@@ -4,7 +4,8 @@ package indexset
 
 import (
 	"fmt"
-	"strings"
+	"math"
+	"math/bits"
 )
 
 const (
@@ -20,31 +21,30 @@ var (
 
 type comparisonPosition int
 
+// Range is the name of indexRange outside this package, so that
+// Implementations and Members can be written elsewhere. Ranges are made with
+// MakeRange and read with Left, Right and Weight.
+type Range = indexRange
+
 type indexRange struct {
 	left   int64
 	right  int64
 	weight int64
 }
 
+// MakeRange returns the range [left, right] with weight. Any int64 can be an
+// index, so the only requirement is that left is not larger than right.
 func MakeRange(left int64, right int64, weight int64) (*indexRange, error) {
-	if left > right {
-		return nil, fmt.Errorf("invalid range: left (%v) is larger than right (%v)", left, right)
-	}
-
-	if left < 0 {
-		return nil, fmt.Errorf("invalid range: left (%v) is less than 0", left)
-	}
-
-	if right < 0 {
-		return nil, fmt.Errorf("invalid range: right (%v) is less than 0", right)
-	}
-
 	val := indexRange{
 		left:   left,
 		right:  right,
 		weight: weight,
 	}
 
+	if left > right {
+		return nil, &RangeError{Op: "make", Ranges: []indexRange{val}, Err: ErrLeftLargerThanRight}
+	}
+
 	return &val, nil
 }
 
@@ -65,6 +65,30 @@ func MakeRanges(ranges ...[3]int64) ([]indexRange, error) {
 	return output, nil
 }
 
+func (r indexRange) Left() int64 {
+	return r.left
+}
+
+func (r indexRange) Right() int64 {
+	return r.right
+}
+
+func (r indexRange) Weight() int64 {
+	return r.weight
+}
+
+// addLength adds the number of indices r covers to total, saturating at
+// math.MaxUint64, which is one short of the length of every int64.
+func (r indexRange) addLength(total uint64) uint64 {
+	total, carry := bits.Add64(total, uint64(r.right-r.left), 1)
+
+	if carry != 0 {
+		return math.MaxUint64
+	}
+
+	return total
+}
+
 func (a indexRange) comparePosition(b indexRange) comparisonPosition {
 	if a.right < b.left {
 		return comparisonPositionRight
@@ -76,32 +100,21 @@ func (a indexRange) comparePosition(b indexRange) comparisonPosition {
 }
 
 func (q indexRange) String() string {
-	repeat := func(str string, count int64) string {
-		if count > 0 {
-			return strings.Repeat(str, int(count))
-		}
-
-		return ""
-	}
-
 	return fmt.Sprintf(
 		"%v:|%v_%v|",
 		q.weight,
 		q.left,
 		q.right,
 	)
-
-	return fmt.Sprintf(
-		"%v|%v%v%v|",
-		repeat(" ", q.left-1),
-		q.left,
-		repeat("_", q.right-q.left-1),
-		q.right,
-	)
 }
 
 func (a indexRange) SplitWith(b indexRange) (replacements []indexRange, carryover indexRange, err error) {
+	return a.splitWith(b, WeightArithmeticChecked)
+}
+
+func (a indexRange) splitWith(b indexRange, arithmetic WeightArithmetic) (replacements []indexRange, carryover indexRange, err error) {
 	relation := makeIndexRangeOverlap(a, b)
+	relation.arithmetic = arithmetic
 	splitFunc := relation.splitFunc()
 	return splitFunc(relation)
 }
@@ -1,9 +1,5 @@
 package indexset
 
-import (
-	"errors"
-)
-
 /*
    ___ ____
 
@@ -22,6 +18,7 @@ import (
 type indexRangeOverlap struct {
 	a, b            indexRange
 	rightIsNewRange bool
+	arithmetic      WeightArithmetic
 }
 
 func makeIndexRangeOverlap(a, b indexRange) indexRangeOverlap {
@@ -70,22 +67,38 @@ func (r indexRangeOverlap) splitFunc() indexRangeSplitFunc {
 	return indexRangeSplitFuncUnknown
 }
 
+func (r indexRangeOverlap) weight() (int64, error) {
+	return r.arithmetic.add(r.a, r.b)
+}
+
 type indexRangeSplitFunc func(indexRangeOverlap) (replacement []indexRange, carryover indexRange, err error)
 
 var (
-	indexRangeSplitFuncUnknown = func(_ indexRangeOverlap) ([]indexRange, indexRange, error) {
-		return nil, indexRangeZero, errors.New("failed to combine nodes: unknown overlap")
+	indexRangeSplitFuncUnknown = func(r indexRangeOverlap) ([]indexRange, indexRange, error) {
+		return nil, indexRangeZero, &RangeError{Op: "split", Ranges: []indexRange{r.a, r.b}, Err: ErrUnknownOverlap}
 	}
 
 	indexRangeSplitFuncEqual = func(r indexRangeOverlap) ([]indexRange, indexRange, error) {
-		replacement, err := MakeRanges([3]int64{r.a.left, r.a.right, r.a.weight + r.b.weight})
+		weight, err := r.weight()
+
+		if err != nil {
+			return nil, indexRangeZero, err
+		}
+
+		replacement, err := MakeRanges([3]int64{r.a.left, r.a.right, weight})
 		return replacement, indexRangeZero, err
 	}
 
 	indexRangeSplitFuncInside = func(r indexRangeOverlap) ([]indexRange, indexRange, error) {
+		weight, err := r.weight()
+
+		if err != nil {
+			return nil, indexRangeZero, err
+		}
+
 		replacement, err := MakeRanges(
 			[3]int64{r.a.left, r.b.left - 1, r.a.weight},
-			[3]int64{r.b.left, r.b.right, r.a.weight + r.b.weight},
+			[3]int64{r.b.left, r.b.right, weight},
 			[3]int64{r.b.right + 1, r.a.right, r.a.weight},
 		)
 
@@ -97,8 +110,14 @@ var (
 	}
 
 	indexRangeSplitFuncLeftInside = func(r indexRangeOverlap) ([]indexRange, indexRange, error) {
+		weight, err := r.weight()
+
+		if err != nil {
+			return nil, indexRangeZero, err
+		}
+
 		replacement, err := MakeRanges(
-			[3]int64{r.a.left, r.b.right, r.a.weight + r.b.weight},
+			[3]int64{r.a.left, r.b.right, weight},
 			[3]int64{r.b.right + 1, r.a.right, r.a.weight},
 		)
 
@@ -110,21 +129,33 @@ var (
 	}
 
 	indexRangeSplitFuncRightInside = func(r indexRangeOverlap) ([]indexRange, indexRange, error) {
+		weight, err := r.weight()
+
+		if err != nil {
+			return nil, indexRangeZero, err
+		}
+
 		replacement, err := MakeRanges(
 			[3]int64{r.a.left, r.b.left - 1, r.a.weight},
-			[3]int64{r.b.left, r.b.right, r.a.weight + r.b.weight},
+			[3]int64{r.b.left, r.b.right, weight},
 		)
 
 		return replacement, indexRangeZero, err
 	}
 
 	indexRangeSplitFuncRightOutside = func(r indexRangeOverlap) ([]indexRange, indexRange, error) {
-		rightRange := [3]int64{r.a.right, r.b.right, r.b.weight}
+		weight, err := r.weight()
+
+		if err != nil {
+			return nil, indexRangeZero, err
+		}
+
+		rightRange := [3]int64{r.a.right + 1, r.b.right, r.b.weight}
 
 		if r.rightIsNewRange {
 			replacement, err := MakeRanges(
 				[3]int64{r.a.left, r.b.left - 1, r.a.weight},
-				[3]int64{r.b.left, r.a.right, r.a.weight + r.b.weight},
+				[3]int64{r.b.left, r.a.right, weight},
 				rightRange,
 			)
 
@@ -132,7 +163,7 @@ var (
 		} else {
 			replacement, err := MakeRanges(
 				[3]int64{r.a.left, r.b.left - 1, r.a.weight},
-				[3]int64{r.b.left, r.a.right, r.a.weight + r.b.weight},
+				[3]int64{r.b.left, r.a.right, weight},
 				rightRange,
 			)
 
@@ -93,7 +93,7 @@ func combineSuccessTestCases() []combineSuccessTestCase {
 				[3]int64{1, 3, 1},
 				[3]int64{4, 5, 2},
 			},
-			indexRange{5, 6, 1},
+			indexRange{6, 6, 1},
 		},
 		{
 			"outside right, primary",
@@ -102,7 +102,7 @@ func combineSuccessTestCases() []combineSuccessTestCase {
 			[][3]int64{
 				[3]int64{1, 3, 1},
 				[3]int64{4, 5, 2},
-				[3]int64{5, 6, 1},
+				[3]int64{6, 6, 1},
 			},
 			indexRangeZero,
 		},
@@ -0,0 +1,486 @@
+// Package indexsethttp serves a collection of named indexset.Sets over HTTP
+// with JSON request and response bodies.
+//
+// Routes, where {name} is created by the first add or subtract:
+//
+//	GET    /sets                            list the names of the sets
+//	DELETE /sets/{name}                     drop a set
+//	POST   /sets/{name}/add                 add a Range
+//	POST   /sets/{name}/subtract            subtract a Range
+//	GET    /sets/{name}/at?index=i          the weight at index i
+//	GET    /sets/{name}/aggregate?left=l&right=r
+//	                                        coverage and weighted sum of [l, r]
+//	GET    /sets/{name}/max                 the largest weight and its segments
+//	GET    /sets/{name}/dump                every segment
+//
+// add and subtract both answer with the segments overlapping the Range once
+// it has been added or subtracted.
+//
+// Errors are returned as an Error body. Invalid ranges and query parameters
+// are answered with 400, unknown sets with 404 and weight overflows with 422.
+package indexsethttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/friedenberg/indexset"
+)
+
+var (
+	errSetNotFound      = errors.New("set not found")
+	errMethodNotAllowed = errors.New("method not allowed")
+	errBadRequest       = errors.New("bad request")
+)
+
+// Range is the body of add and subtract requests and an element of the
+// segments returned by max and dump.
+type Range struct {
+	Left   int64 `json:"left"`
+	Right  int64 `json:"right"`
+	Weight int64 `json:"weight"`
+}
+
+type Names struct {
+	Names []string `json:"names"`
+}
+
+type Point struct {
+	Index  int64 `json:"index"`
+	Weight int64 `json:"weight"`
+}
+
+// Aggregate summarizes the window [Left, Right]. Covered is the number of
+// indices with a segment, saturating at math.MaxUint64, Sum adds up the weight of every index and Max is
+// the largest weight, or 0 if every weight is below 0.
+type Aggregate struct {
+	Left    int64  `json:"left"`
+	Right   int64  `json:"right"`
+	Covered uint64 `json:"covered"`
+	Sum     int64  `json:"sum"`
+	Max     int64  `json:"max"`
+}
+
+// Max holds the largest weight of a set and the segments with that weight.
+// ArgMax is empty when the largest weight is 0.
+type Max struct {
+	Max    int64   `json:"max"`
+	ArgMax []Range `json:"argmax"`
+}
+
+type Segments struct {
+	Segments []Range `json:"segments"`
+}
+
+type Error struct {
+	Error string `json:"error"`
+}
+
+// namedSet is marked deleted under its lock once it is dropped from the
+// Handler, so that requests that looked it up before then can tell.
+type namedSet struct {
+	sync.Mutex
+	set     *indexset.Set
+	deleted bool
+}
+
+// Handler holds the sets in memory. It is safe for concurrent use, and
+// requests for different sets do not block each other.
+type Handler struct {
+	newImplementation func() indexset.Implementation
+	lock              sync.RWMutex
+	sets              map[string]*namedSet
+}
+
+func NewHandler(newImplementation func() indexset.Implementation) *Handler {
+	return &Handler{
+		newImplementation: newImplementation,
+		sets:              make(map[string]*namedSet),
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	response, err := h.route(r)
+
+	if err != nil {
+		writeJSON(w, statusOf(err), Error{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) route(r *http.Request) (any, error) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	if parts[0] != "sets" || len(parts) > 3 {
+		return nil, fmt.Errorf("%w: %s", errSetNotFound, r.URL.Path)
+	}
+
+	switch len(parts) {
+	case 1:
+		if r.Method != http.MethodGet {
+			return nil, errMethodNotAllowed
+		}
+
+		return h.names(), nil
+
+	case 2:
+		if r.Method != http.MethodDelete {
+			return nil, errMethodNotAllowed
+		}
+
+		return h.delete(parts[1])
+	}
+
+	name, operation := parts[1], parts[2]
+
+	switch operation {
+	case "add", "subtract":
+		if r.Method != http.MethodPost {
+			return nil, errMethodNotAllowed
+		}
+
+		return h.mutate(name, operation, r)
+
+	case "at", "aggregate", "max", "dump":
+		if r.Method != http.MethodGet {
+			return nil, errMethodNotAllowed
+		}
+
+		return h.query(name, operation, r)
+
+	default:
+		return nil, fmt.Errorf("%w: %s", errSetNotFound, r.URL.Path)
+	}
+}
+
+func (h *Handler) names() Names {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	names := make([]string, 0, len(h.sets))
+
+	for name := range h.sets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return Names{Names: names}
+}
+
+func (h *Handler) delete(name string) (Names, error) {
+	h.lock.Lock()
+	set, ok := h.sets[name]
+	delete(h.sets, name)
+	h.lock.Unlock()
+
+	if !ok {
+		return Names{}, fmt.Errorf("%w: %s", errSetNotFound, name)
+	}
+
+	set.Lock()
+	set.deleted = true
+	set.Unlock()
+
+	return Names{Names: []string{name}}, nil
+}
+
+// get returns the set called name, creating it first if create is true.
+func (h *Handler) get(name string, create bool) (*namedSet, error) {
+	h.lock.RLock()
+	set, ok := h.sets[name]
+	h.lock.RUnlock()
+
+	if ok {
+		return set, nil
+	}
+
+	if !create {
+		return nil, fmt.Errorf("%w: %s", errSetNotFound, name)
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if set, ok = h.sets[name]; !ok {
+		set = &namedSet{set: &indexset.Set{Implementation: h.newImplementation()}}
+		h.sets[name] = set
+	}
+
+	return set, nil
+}
+
+// acquire returns the set called name locked, creating it first if create
+// is true. A set deleted while waiting for its lock is looked up again, so
+// that no request writes to or reads from a dropped set.
+func (h *Handler) acquire(name string, create bool) (*namedSet, error) {
+	for {
+		set, err := h.get(name, create)
+
+		if err != nil {
+			return nil, err
+		}
+
+		set.Lock()
+
+		if !set.deleted {
+			return set, nil
+		}
+
+		set.Unlock()
+	}
+}
+
+func (h *Handler) mutate(name, operation string, r *http.Request) (Segments, error) {
+	var body Range
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return Segments{}, fmt.Errorf("%w: %s", errBadRequest, err)
+	}
+
+	newRange, err := indexset.MakeRange(body.Left, body.Right, body.Weight)
+
+	if err != nil {
+		return Segments{}, err
+	}
+
+	set, err := h.acquire(name, true)
+
+	if err != nil {
+		return Segments{}, err
+	}
+
+	defer set.Unlock()
+
+	if operation == "subtract" {
+		err = set.set.Subtract(*newRange)
+	} else {
+		err = set.set.Add(*newRange)
+	}
+
+	if err != nil {
+		return Segments{}, err
+	}
+
+	segments := make([]Range, 0)
+
+	for _, m := range set.set.FindOverlapping(*newRange) {
+		segments = append(segments, makeRange(m))
+	}
+
+	return Segments{Segments: segments}, nil
+}
+
+func (h *Handler) query(name, operation string, r *http.Request) (any, error) {
+	set, err := h.acquire(name, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer set.Unlock()
+
+	query := r.URL.Query()
+
+	switch operation {
+	case "at":
+		index, err := parseInt(query, "index")
+
+		if err != nil {
+			return nil, err
+		}
+
+		return Point{Index: index, Weight: set.set.At(index)}, nil
+
+	case "aggregate":
+		left, err := parseInt(query, "left")
+
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := parseInt(query, "right")
+
+		if err != nil {
+			return nil, err
+		}
+
+		window, err := indexset.MakeRange(left, right, 0)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return aggregate(set.set.FindOverlapping(*window), left, right)
+
+	case "max":
+		return argMax(set.set)
+
+	default:
+		return dump(set.set)
+	}
+}
+
+func parseInt(query map[string][]string, key string) (int64, error) {
+	values, ok := query[key]
+
+	if !ok || len(values) != 1 {
+		return 0, fmt.Errorf("%w: expected one %s parameter", errBadRequest, key)
+	}
+
+	value, err := strconv.ParseInt(values[0], 10, 64)
+
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s: %s", errBadRequest, key, err)
+	}
+
+	return value, nil
+}
+
+// aggregate summarizes the members overlapping the window [left, right].
+func aggregate(overlapping []indexset.Member, left, right int64) (Aggregate, error) {
+	result := Aggregate{Left: left, Right: right}
+
+	for _, m := range overlapping {
+		r := m.IndexRange()
+		segmentLeft, segmentRight := r.Left(), r.Right()
+
+		if segmentLeft < left {
+			segmentLeft = left
+		}
+
+		if segmentRight > right {
+			segmentRight = right
+		}
+
+		//wraps to 0 for a segment covering every int64
+		length := uint64(segmentRight-segmentLeft) + 1
+		covered, carry := bits.Add64(result.Covered, uint64(segmentRight-segmentLeft), 1)
+
+		if carry != 0 {
+			covered = math.MaxUint64
+		}
+
+		result.Covered = covered
+
+		sum, err := addProduct(result.Sum, r.Weight(), length)
+
+		if err != nil {
+			return Aggregate{}, err
+		}
+
+		result.Sum = sum
+
+		if r.Weight() > result.Max {
+			result.Max = r.Weight()
+		}
+	}
+
+	return result, nil
+}
+
+// addProduct returns sum + weight*length, or ErrWeightOverflow if that does
+// not fit in an int64. A length of 0 stands for 2^64.
+func addProduct(sum, weight int64, length uint64) (int64, error) {
+	magnitude, limit := uint64(weight), uint64(math.MaxInt64)
+
+	//-math.MinInt64 does not fit in an int64, but the negated product does
+	if weight < 0 {
+		magnitude, limit = -magnitude, limit+1
+	}
+
+	hi, lo := bits.Mul64(magnitude, length)
+
+	if length == 0 {
+		hi, lo = magnitude, 0
+	}
+
+	if hi != 0 || lo > limit {
+		return 0, indexset.ErrWeightOverflow
+	}
+
+	product := int64(lo)
+
+	if weight < 0 {
+		product = int64(-lo)
+	}
+
+	if (product > 0 && sum > math.MaxInt64-product) || (product < 0 && sum < math.MinInt64-product) {
+		return 0, indexset.ErrWeightOverflow
+	}
+
+	return sum + product, nil
+}
+
+func argMax(s *indexset.Set) (Max, error) {
+	result := Max{Max: s.Max(), ArgMax: make([]Range, 0)}
+
+	if result.Max == 0 {
+		return result, nil
+	}
+
+	err := s.Do(
+		func(m indexset.Member) bool {
+			if r := m.IndexRange(); r.Weight() == result.Max {
+				result.ArgMax = append(result.ArgMax, makeRange(m))
+			}
+
+			return false
+		},
+	)
+
+	return result, err
+}
+
+func dump(s *indexset.Set) (Segments, error) {
+	segments := make([]Range, 0)
+
+	err := s.Do(
+		func(m indexset.Member) bool {
+			segments = append(segments, makeRange(m))
+			return false
+		},
+	)
+
+	return Segments{Segments: segments}, err
+}
+
+func makeRange(m indexset.Member) Range {
+	r := m.IndexRange()
+	return Range{Left: r.Left(), Right: r.Right(), Weight: r.Weight()}
+}
+
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, errBadRequest), errors.Is(err, indexset.ErrInvalidRange):
+		return http.StatusBadRequest
+
+	case errors.Is(err, errSetNotFound):
+		return http.StatusNotFound
+
+	case errors.Is(err, errMethodNotAllowed):
+		return http.StatusMethodNotAllowed
+
+	case errors.Is(err, indexset.ErrWeightOverflow):
+		return http.StatusUnprocessableEntity
+
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
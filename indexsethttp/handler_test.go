@@ -0,0 +1,282 @@
+package indexsethttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/friedenberg/indexset"
+	"github.com/stretchr/testify/assert"
+)
+
+type request struct {
+	method         string
+	path           string
+	body           string
+	expectedStatus int
+	expectedBody   string
+}
+
+func runRequests(t *testing.T, handler http.Handler, requests []request) {
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	for _, r := range requests {
+		req, err := http.NewRequest(r.method, server.URL+r.path, strings.NewReader(r.body))
+		assert.Nil(t, err)
+
+		resp, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err)
+
+		var body json.RawMessage
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(&body))
+		resp.Body.Close()
+
+		description := fmt.Sprintf("%s %s", r.method, r.path)
+		assert.Equal(t, r.expectedStatus, resp.StatusCode, description)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"), description)
+
+		if r.expectedBody != "" {
+			assert.JSONEq(t, r.expectedBody, string(body), description)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	for name, newImplementation := range indexset.Implementations() {
+		t.Run(name, func(t *testing.T) {
+			runRequests(
+				t,
+				NewHandler(newImplementation),
+				[]request{
+					{
+						method:         http.MethodGet,
+						path:           "/sets",
+						expectedStatus: http.StatusOK,
+						expectedBody:   `{"names": []}`,
+					},
+					{
+						method:         http.MethodPost,
+						path:           "/sets/a/add",
+						body:           `{"left": 1, "right": 5, "weight": 2}`,
+						expectedStatus: http.StatusOK,
+						expectedBody:   `{"segments": [{"left": 1, "right": 5, "weight": 2}]}`,
+					},
+					{
+						method:         http.MethodPost,
+						path:           "/sets/a/add",
+						body:           `{"left": 4, "right": 8, "weight": 3}`,
+						expectedStatus: http.StatusOK,
+						expectedBody: `{"segments": [
+							{"left": 4, "right": 5, "weight": 5},
+							{"left": 6, "right": 8, "weight": 3}
+						]}`,
+					},
+					{
+						method:         http.MethodPost,
+						path:           "/sets/a/subtract",
+						body:           `{"left": 1, "right": 2, "weight": 2}`,
+						expectedStatus: http.StatusOK,
+						expectedBody:   `{"segments": []}`,
+					},
+					{
+						method:         http.MethodGet,
+						path:           "/sets/a/at?index=4",
+						expectedStatus: http.StatusOK,
+						expectedBody:   `{"index": 4, "weight": 5}`,
+					},
+					{
+						method:         http.MethodGet,
+						path:           "/sets/a/at?index=0",
+						expectedStatus: http.StatusOK,
+						expectedBody:   `{"index": 0, "weight": 0}`,
+					},
+					{
+						method:         http.MethodGet,
+						path:           "/sets/a/aggregate?left=0&right=6",
+						expectedStatus: http.StatusOK,
+						expectedBody:   `{"left": 0, "right": 6, "covered": 4, "sum": 15, "max": 5}`,
+					},
+					{
+						method:         http.MethodGet,
+						path:           "/sets/a/max",
+						expectedStatus: http.StatusOK,
+						expectedBody:   `{"max": 5, "argmax": [{"left": 4, "right": 5, "weight": 5}]}`,
+					},
+					{
+						method:         http.MethodPost,
+						path:           "/sets/b/add",
+						body:           `{"left": 0, "right": 0, "weight": 1}`,
+						expectedStatus: http.StatusOK,
+					},
+					{
+						method:         http.MethodGet,
+						path:           "/sets",
+						expectedStatus: http.StatusOK,
+						expectedBody:   `{"names": ["a", "b"]}`,
+					},
+					{
+						method:         http.MethodDelete,
+						path:           "/sets/b",
+						expectedStatus: http.StatusOK,
+						expectedBody:   `{"names": ["b"]}`,
+					},
+					{
+						method:         http.MethodGet,
+						path:           "/sets/b/dump",
+						expectedStatus: http.StatusNotFound,
+						expectedBody:   `{"error": "set not found: b"}`,
+					},
+				},
+			)
+		})
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	runRequests(
+		t,
+		NewHandler(indexset.NewPersistentTree),
+		[]request{
+			{
+				method:         http.MethodPost,
+				path:           "/sets/a/add",
+				body:           `{"left": 5, "right": 1, "weight": 1}`,
+				expectedStatus: http.StatusBadRequest,
+			},
+			{
+				method:         http.MethodPost,
+				path:           "/sets/a/add",
+				body:           `{"left": 1`,
+				expectedStatus: http.StatusBadRequest,
+			},
+			{
+				method:         http.MethodGet,
+				path:           "/sets/a/add",
+				expectedStatus: http.StatusMethodNotAllowed,
+			},
+			{
+				method:         http.MethodPost,
+				path:           "/sets/a/add",
+				body:           fmt.Sprintf(`{"left": 0, "right": 1, "weight": %d}`, int64(math.MaxInt64)),
+				expectedStatus: http.StatusOK,
+			},
+			{
+				method:         http.MethodPost,
+				path:           "/sets/a/add",
+				body:           `{"left": 0, "right": 0, "weight": 1}`,
+				expectedStatus: http.StatusUnprocessableEntity,
+			},
+			{
+				method:         http.MethodGet,
+				path:           "/sets/a/aggregate?left=0&right=1",
+				expectedStatus: http.StatusUnprocessableEntity,
+			},
+			{
+				method:         http.MethodGet,
+				path:           "/sets/a/at?index=x",
+				expectedStatus: http.StatusBadRequest,
+			},
+			{
+				method:         http.MethodGet,
+				path:           "/sets/a/aggregate?left=0",
+				expectedStatus: http.StatusBadRequest,
+			},
+			{
+				method:         http.MethodGet,
+				path:           "/sets/a/unknown",
+				expectedStatus: http.StatusNotFound,
+			},
+			{
+				method:         http.MethodGet,
+				path:           "/other",
+				expectedStatus: http.StatusNotFound,
+			},
+			{
+				method:         http.MethodDelete,
+				path:           "/sets/missing",
+				expectedStatus: http.StatusNotFound,
+			},
+			{
+				method:         http.MethodPost,
+				path:           "/sets/whole/add",
+				body:           fmt.Sprintf(`{"left": %d, "right": %d, "weight": 1}`, int64(math.MinInt64), int64(math.MaxInt64)),
+				expectedStatus: http.StatusOK,
+			},
+			{
+				method:         http.MethodGet,
+				path:           fmt.Sprintf("/sets/whole/aggregate?left=%d&right=%d", int64(math.MinInt64), int64(math.MaxInt64)),
+				expectedStatus: http.StatusUnprocessableEntity,
+			},
+		},
+	)
+}
+
+func TestHandlerDeleteWaitsForRequests(t *testing.T) {
+	h := NewHandler(indexset.NewLinkedList)
+	set, err := h.acquire("a", true)
+	assert.Nil(t, err)
+
+	deleted := make(chan error)
+
+	go func() {
+		_, err := h.delete("a")
+		deleted <- err
+	}()
+
+	for {
+		h.lock.RLock()
+		_, ok := h.sets["a"]
+		h.lock.RUnlock()
+
+		if !ok {
+			break
+		}
+
+		runtime.Gosched()
+	}
+
+	//the request holding the set finishes before delete returns
+	select {
+	case <-deleted:
+		t.Fatal("delete returned while the set was locked")
+	default:
+	}
+
+	set.Unlock()
+	assert.Nil(t, <-deleted)
+	assert.True(t, set.deleted)
+
+	_, err = h.acquire("a", false)
+	assert.ErrorIs(t, err, errSetNotFound)
+
+	fresh, err := h.acquire("a", true)
+	assert.Nil(t, err)
+	assert.NotSame(t, set, fresh)
+	fresh.Unlock()
+}
+
+func TestAddProduct(t *testing.T) {
+	sum, err := addProduct(1, math.MinInt64/2, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(math.MinInt64+1), sum)
+
+	sum, err = addProduct(0, -1, 1<<63)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(math.MinInt64), sum)
+
+	_, err = addProduct(0, 1, 1<<63)
+	assert.ErrorIs(t, err, indexset.ErrWeightOverflow)
+
+	_, err = addProduct(-1, -1, 1<<63)
+	assert.ErrorIs(t, err, indexset.ErrWeightOverflow)
+
+	//a length of 0 is 2^64
+	_, err = addProduct(0, -1, 0)
+	assert.ErrorIs(t, err, indexset.ErrWeightOverflow)
+}
@@ -0,0 +1,358 @@
+// Package indexsetnetip sums weights over IPv4 and IPv6 addresses, such as
+// the number of firewall rules matching each address, and turns covered
+// addresses back into a minimal list of CIDR prefixes.
+//
+// IPv4 addresses are the coordinates of an indexset.Set. IPv6 addresses
+// need 128 bits, so they are split into their high and low 64 bits, which
+// are the rows and columns of an indexset.Set2D. A range of addresses is at
+// most three rectangles: the end of its first row, the whole rows in
+// between and the start of its last row.
+package indexsetnetip
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+	"sort"
+
+	"github.com/friedenberg/indexset"
+)
+
+// Range is the addresses from From to To, inclusive, which must be of the
+// same family.
+type Range struct {
+	From   netip.Addr
+	To     netip.Addr
+	Weight int64
+}
+
+// Set sums weighted ranges of addresses. IPv4-mapped IPv6 addresses are
+// IPv6 addresses. A Set is not safe for concurrent use.
+type Set struct {
+	v4 *indexset.Set
+	v6 *indexset.Set2D
+}
+
+// New returns an empty Set whose segments use newImplementation.
+func New(newImplementation func() indexset.Implementation) *Set {
+	return &Set{
+		v4: &indexset.Set{Implementation: newImplementation()},
+		v6: indexset.NewSet2D(newImplementation),
+	}
+}
+
+// AddPrefix adds weight to every address in prefix.
+func (s *Set) AddPrefix(prefix netip.Prefix, weight int64) error {
+	from, to, err := bounds(prefix)
+
+	if err != nil {
+		return err
+	}
+
+	return s.AddRange(from, to, weight)
+}
+
+// SubtractPrefix takes weight from every address in prefix.
+func (s *Set) SubtractPrefix(prefix netip.Prefix, weight int64) error {
+	from, to, err := bounds(prefix)
+
+	if err != nil {
+		return err
+	}
+
+	return s.SubtractRange(from, to, weight)
+}
+
+// bounds returns the first and last addresses of prefix.
+func bounds(prefix netip.Prefix) (from, to netip.Addr, err error) {
+	if !prefix.IsValid() {
+		return from, to, fmt.Errorf("%w: invalid prefix %s", indexset.ErrInvalidRange, prefix)
+	}
+
+	prefix = prefix.Masked()
+	first := fromAddr(prefix.Addr())
+	last := first.or(ones(prefix.Addr().BitLen() - prefix.Bits()))
+
+	return prefix.Addr(), last.addr(prefix.Addr().Is4()), nil
+}
+
+// AddRange adds weight to every address from from to to, inclusive. If it
+// fails, the set is left as it was before the call.
+func (s *Set) AddRange(from, to netip.Addr, weight int64) error {
+	if err := check(from, to); err != nil {
+		return err
+	}
+
+	if weight == 0 {
+		return nil
+	}
+
+	if from.Is4() {
+		r, err := indexset.MakeRange(int64(fromAddr(from).lo), int64(fromAddr(to).lo), weight)
+
+		if err != nil {
+			return err
+		}
+
+		return s.v4.Add(*r)
+	}
+
+	undo := make([]func(), 0, 3)
+
+	for _, piece := range rectangles(fromAddr(from), fromAddr(to)) {
+		r, err := indexset.MakeRectangle(
+			coordinate(piece.left),
+			coordinate(piece.right),
+			coordinate(piece.bottom),
+			coordinate(piece.top),
+			weight,
+		)
+
+		if err == nil {
+			err = s.v6.Add(*r)
+		}
+
+		if err != nil {
+			//the rectangles added so far can always be taken out again
+			for _, f := range undo {
+				f()
+			}
+
+			return err
+		}
+
+		undo = append(undo, func() { s.v6.Subtract(*r) })
+	}
+
+	return nil
+}
+
+// SubtractRange takes weight from every address from from to to, inclusive.
+func (s *Set) SubtractRange(from, to netip.Addr, weight int64) error {
+	if weight == math.MinInt64 {
+		return fmt.Errorf("%w: cannot negate %d", indexset.ErrWeightOverflow, weight)
+	}
+
+	return s.AddRange(from, to, -weight)
+}
+
+func check(from, to netip.Addr) error {
+	if !from.IsValid() || !to.IsValid() {
+		return fmt.Errorf("%w: invalid address in %s-%s", indexset.ErrInvalidRange, from, to)
+	}
+
+	if from.Is4() != to.Is4() {
+		return fmt.Errorf("%w: %s and %s are of different families", indexset.ErrInvalidRange, from, to)
+	}
+
+	if to.Less(from) {
+		return fmt.Errorf("%w: %s is after %s", indexset.ErrLeftLargerThanRight, from, to)
+	}
+
+	return nil
+}
+
+// rows are the columns [left, right] of the rows [bottom, top] of the IPv6
+// addresses.
+type rows struct {
+	bottom, top, left, right uint64
+}
+
+// rectangles splits the IPv6 addresses [from, to] into rows, merging the
+// first and last rows into the whole rows in between when they are whole
+// too.
+func rectangles(from, to uint128) []rows {
+	if from.hi == to.hi {
+		return []rows{{from.hi, from.hi, from.lo, to.lo}}
+	}
+
+	pieces := make([]rows, 0, 3)
+	bottom, top := from.hi, to.hi
+
+	if from.lo != 0 {
+		pieces = append(pieces, rows{from.hi, from.hi, from.lo, math.MaxUint64})
+		bottom++
+	}
+
+	if to.lo != math.MaxUint64 {
+		top--
+	}
+
+	if bottom <= top {
+		pieces = append(pieces, rows{bottom, top, 0, math.MaxUint64})
+	}
+
+	if to.lo != math.MaxUint64 {
+		pieces = append(pieces, rows{to.hi, to.hi, 0, to.lo})
+	}
+
+	return pieces
+}
+
+// At returns the weight of addr, or 0 if it is not valid.
+func (s *Set) At(addr netip.Addr) int64 {
+	if !addr.IsValid() {
+		return 0
+	}
+
+	u := fromAddr(addr)
+
+	if addr.Is4() {
+		return s.v4.At(int64(u.lo))
+	}
+
+	return s.v6.At(coordinate(u.lo), coordinate(u.hi))
+}
+
+// Max returns the largest weight of any address, or 0 if none is larger.
+func (s *Set) Max() int64 {
+	if max := s.v6.Max(); max > s.v4.Max() {
+		return max
+	}
+
+	return s.v4.Max()
+}
+
+// Ranges returns the maximal ranges of addresses of the same weight, IPv4
+// before IPv6 and each in order.
+func (s *Set) Ranges() ([]Range, error) {
+	ranges := make([]Range, 0)
+
+	appendRange := func(from, to uint128, is4 bool, weight int64) {
+		last := len(ranges) - 1
+
+		if last >= 0 && ranges[last].Weight == weight && ranges[last].From.Is4() == is4 {
+			if next, ok := fromAddr(ranges[last].To).next(); ok && next == from {
+				ranges[last].To = to.addr(is4)
+				return
+			}
+		}
+
+		ranges = append(ranges, Range{From: from.addr(is4), To: to.addr(is4), Weight: weight})
+	}
+
+	err := s.v4.Do(
+		func(m indexset.Member) bool {
+			r := m.IndexRange()
+			appendRange(uint128{lo: uint64(r.Left())}, uint128{lo: uint64(r.Right())}, true, r.Weight())
+			return false
+		},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rectangles, err := s.v6.Rectangles()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range rectangles {
+		from := uint128{half(r.Bottom()), half(r.Left())}
+		to := uint128{half(r.Top()), half(r.Right())}
+
+		//AddRange only leaves whole rows in rectangles of more than one row
+		if r.Bottom() != r.Top() && (from.lo != 0 || to.lo != math.MaxUint64) {
+			return nil, fmt.Errorf("%w: partial rows in %s", indexset.ErrCorrupt, r)
+		}
+
+		appendRange(from, to, false, r.Weight())
+	}
+
+	return ranges, nil
+}
+
+// Prefixes returns the fewest prefixes covering exactly the addresses of
+// any weight other than 0.
+func (s *Set) Prefixes() ([]netip.Prefix, error) {
+	ranges, err := s.Ranges()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Prefixes(ranges...)
+}
+
+// Prefixes returns the fewest prefixes covering exactly the addresses in
+// ranges, ignoring their weights. Overlapping and adjacent ranges are
+// merged first, and IPv4 prefixes come before IPv6 ones.
+func Prefixes(ranges ...Range) ([]netip.Prefix, error) {
+	for _, r := range ranges {
+		if err := check(r.From, r.To); err != nil {
+			return nil, err
+		}
+	}
+
+	sorted := append([]Range(nil), ranges...)
+
+	sort.Slice(
+		sorted,
+		func(i, j int) bool {
+			if sorted[i].From.Is4() != sorted[j].From.Is4() {
+				return sorted[i].From.Is4()
+			}
+
+			return sorted[i].From.Less(sorted[j].From)
+		},
+	)
+
+	prefixes := make([]netip.Prefix, 0)
+
+	for i := 0; i < len(sorted); {
+		is4 := sorted[i].From.Is4()
+		from, to := fromAddr(sorted[i].From), fromAddr(sorted[i].To)
+
+		for i++; i < len(sorted) && sorted[i].From.Is4() == is4; i++ {
+			next, ok := to.next()
+
+			//to is the last address, so it already covers the rest
+			if ok && next.less(fromAddr(sorted[i].From)) {
+				break
+			}
+
+			if to.less(fromAddr(sorted[i].To)) {
+				to = fromAddr(sorted[i].To)
+			}
+		}
+
+		prefixes = cover(prefixes, from, to, is4)
+	}
+
+	return prefixes, nil
+}
+
+// cover appends the fewest prefixes covering [from, to], each the largest
+// block that starts at the first address not yet covered and ends within
+// to.
+func cover(prefixes []netip.Prefix, from, to uint128, is4 bool) []netip.Prefix {
+	width := 128
+
+	if is4 {
+		width = 32
+	}
+
+	for {
+		//the block of 2^bits addresses must be aligned and fit before to
+		bits := from.trailingZeros()
+
+		if bits > width {
+			bits = width
+		}
+
+		if count, ok := to.sub(from).next(); ok && count.bitLen()-1 < bits {
+			bits = count.bitLen() - 1
+		}
+
+		prefixes = append(prefixes, netip.PrefixFrom(from.addr(is4), width-bits))
+		last := from.or(ones(bits))
+
+		if last == to {
+			return prefixes
+		}
+
+		from, _ = last.next()
+	}
+}
@@ -0,0 +1,314 @@
+package indexsetnetip
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"net/netip"
+	"testing"
+
+	"github.com/friedenberg/indexset"
+	"github.com/stretchr/testify/assert"
+)
+
+func addr(s string) netip.Addr {
+	return netip.MustParseAddr(s)
+}
+
+func prefixes(strings ...string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(strings))
+
+	for _, s := range strings {
+		prefixes = append(prefixes, netip.MustParsePrefix(s))
+	}
+
+	return prefixes
+}
+
+func TestSetIPv4(t *testing.T) {
+	for name, newImplementation := range indexset.Implementations() {
+		t.Run(name, func(t *testing.T) {
+			s := New(newImplementation)
+			assert.Nil(t, s.AddPrefix(netip.MustParsePrefix("192.168.1.0/24"), 1))
+			assert.Nil(t, s.AddRange(addr("192.168.1.5"), addr("192.168.1.9"), 2))
+			assert.Nil(t, s.AddPrefix(netip.MustParsePrefix("10.1.2.3/8"), 1))
+
+			assert.Equal(t, int64(1), s.At(addr("192.168.1.0")))
+			assert.Equal(t, int64(3), s.At(addr("192.168.1.5")))
+			assert.Equal(t, int64(3), s.At(addr("192.168.1.9")))
+			assert.Equal(t, int64(1), s.At(addr("192.168.1.10")))
+			assert.Equal(t, int64(0), s.At(addr("192.168.2.0")))
+			assert.Equal(t, int64(1), s.At(addr("10.255.255.255")))
+			assert.Equal(t, int64(0), s.At(addr("::ffff:10.0.0.1")))
+			assert.Equal(t, int64(0), s.At(netip.Addr{}))
+			assert.Equal(t, int64(3), s.Max())
+
+			ranges, err := s.Ranges()
+			assert.Nil(t, err)
+			assert.Equal(
+				t,
+				[]Range{
+					{addr("10.0.0.0"), addr("10.255.255.255"), 1},
+					{addr("192.168.1.0"), addr("192.168.1.4"), 1},
+					{addr("192.168.1.5"), addr("192.168.1.9"), 3},
+					{addr("192.168.1.10"), addr("192.168.1.255"), 1},
+				},
+				ranges,
+			)
+
+			cover, err := s.Prefixes()
+			assert.Nil(t, err)
+			assert.Equal(t, prefixes("10.0.0.0/8", "192.168.1.0/24"), cover)
+
+			assert.Nil(t, s.SubtractPrefix(netip.MustParsePrefix("192.168.1.0/24"), 1))
+
+			cover, err = s.Prefixes()
+			assert.Nil(t, err)
+			assert.Equal(t, prefixes("10.0.0.0/8", "192.168.1.5/32", "192.168.1.6/31", "192.168.1.8/31"), cover)
+
+			assert.Nil(t, s.SubtractRange(addr("192.168.1.5"), addr("192.168.1.9"), 2))
+			assert.Nil(t, s.SubtractPrefix(netip.MustParsePrefix("10.0.0.0/8"), 1))
+
+			ranges, err = s.Ranges()
+			assert.Nil(t, err)
+			assert.Empty(t, ranges)
+		})
+	}
+}
+
+func TestSetIPv6(t *testing.T) {
+	for name, newImplementation := range indexset.Implementations() {
+		t.Run(name, func(t *testing.T) {
+			s := New(newImplementation)
+
+			//the end of row 1, all of row 2 and the start of row 3
+			from, to := addr("2001:db8:0:1:ffff:ffff:ffff:fff0"), addr("2001:db8:0:3::f")
+			assert.Nil(t, s.AddRange(from, to, 2))
+
+			assert.Equal(t, int64(0), s.At(addr("2001:db8:0:1:ffff:ffff:ffff:ffef")))
+			assert.Equal(t, int64(2), s.At(from))
+			assert.Equal(t, int64(2), s.At(addr("2001:db8:0:2::")))
+			assert.Equal(t, int64(2), s.At(addr("2001:db8:0:2:ffff:ffff:ffff:ffff")))
+			assert.Equal(t, int64(2), s.At(to))
+			assert.Equal(t, int64(0), s.At(addr("2001:db8:0:3::10")))
+			assert.Equal(t, int64(0), s.At(addr("192.168.1.1")))
+
+			ranges, err := s.Ranges()
+			assert.Nil(t, err)
+			assert.Equal(t, []Range{{from, to, 2}}, ranges)
+
+			cover, err := s.Prefixes()
+			assert.Nil(t, err)
+			assert.Equal(
+				t,
+				prefixes("2001:db8:0:1:ffff:ffff:ffff:fff0/124", "2001:db8:0:2::/64", "2001:db8:0:3::/124"),
+				cover,
+			)
+
+			assert.Nil(t, s.SubtractPrefix(netip.MustParsePrefix("2001:db8:0:2::/64"), 2))
+
+			ranges, err = s.Ranges()
+			assert.Nil(t, err)
+			assert.Equal(
+				t,
+				[]Range{
+					{from, addr("2001:db8:0:1:ffff:ffff:ffff:ffff"), 2},
+					{addr("2001:db8:0:3::"), to, 2},
+				},
+				ranges,
+			)
+
+			assert.Nil(t, s.SubtractRange(from, to, 2))
+			assert.Nil(t, s.AddPrefix(netip.MustParsePrefix("2001:db8:0:2::/64"), 2))
+
+			ranges, err = s.Ranges()
+			assert.Nil(t, err)
+			assert.Empty(t, ranges)
+		})
+	}
+}
+
+func TestSetWholeSpace(t *testing.T) {
+	for name, newImplementation := range indexset.Implementations() {
+		t.Run(name, func(t *testing.T) {
+			s := New(newImplementation)
+			assert.Nil(t, s.AddPrefix(netip.MustParsePrefix("::/0"), 1))
+			assert.Nil(t, s.AddPrefix(netip.MustParsePrefix("0.0.0.0/0"), 1))
+			assert.Nil(t, s.AddPrefix(netip.MustParsePrefix("ffff::/16"), 1))
+
+			assert.Equal(t, int64(1), s.At(addr("::")))
+			assert.Equal(t, int64(2), s.At(addr("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff")))
+			assert.Equal(t, int64(1), s.At(addr("255.255.255.255")))
+
+			ranges, err := s.Ranges()
+			assert.Nil(t, err)
+			assert.Equal(
+				t,
+				[]Range{
+					{addr("0.0.0.0"), addr("255.255.255.255"), 1},
+					{addr("::"), addr("fffe:ffff:ffff:ffff:ffff:ffff:ffff:ffff"), 1},
+					{addr("ffff::"), addr("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"), 2},
+				},
+				ranges,
+			)
+
+			cover, err := s.Prefixes()
+			assert.Nil(t, err)
+			assert.Equal(t, prefixes("0.0.0.0/0", "::/0"), cover)
+		})
+	}
+}
+
+func TestSetErrors(t *testing.T) {
+	s := New(indexset.NewPersistentTree)
+
+	err := s.AddRange(addr("10.0.0.1"), addr("::1"), 1)
+	assert.True(t, errors.Is(err, indexset.ErrInvalidRange))
+
+	err = s.AddRange(addr("10.0.0.2"), addr("10.0.0.1"), 1)
+	assert.True(t, errors.Is(err, indexset.ErrInvalidRange))
+
+	err = s.AddRange(netip.Addr{}, addr("10.0.0.1"), 1)
+	assert.True(t, errors.Is(err, indexset.ErrInvalidRange))
+
+	err = s.AddPrefix(netip.Prefix{}, 1)
+	assert.True(t, errors.Is(err, indexset.ErrInvalidRange))
+
+	err = s.SubtractRange(addr("::1"), addr("::2"), math.MinInt64)
+	assert.True(t, errors.Is(err, indexset.ErrWeightOverflow))
+
+	_, err = Prefixes(Range{addr("::2"), addr("::1"), 1})
+	assert.True(t, errors.Is(err, indexset.ErrInvalidRange))
+
+	//the last of the three rectangles overflows, so the first two are undone
+	assert.Nil(t, s.AddPrefix(netip.MustParsePrefix("2001:db8:0:3::/120"), math.MaxInt64))
+	assert.Nil(t, s.AddPrefix(netip.MustParsePrefix("10.0.0.0/8"), 1))
+	before, err := s.Ranges()
+	assert.Nil(t, err)
+
+	err = s.AddRange(addr("2001:db8:0:1::8"), addr("2001:db8:0:3::f"), 1)
+	assert.True(t, errors.Is(err, indexset.ErrWeightOverflow))
+
+	after, err := s.Ranges()
+	assert.Nil(t, err)
+	assert.Equal(t, before, after)
+}
+
+func TestPrefixes(t *testing.T) {
+	for _, test := range []struct {
+		ranges   []Range
+		expected []netip.Prefix
+	}{
+		{nil, prefixes()},
+		{[]Range{{addr("10.0.0.0"), addr("10.0.0.0"), 0}}, prefixes("10.0.0.0/32")},
+		{[]Range{{addr("10.0.0.1"), addr("10.0.0.6"), 0}}, prefixes("10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/31", "10.0.0.6/32")},
+		{
+			//overlapping and adjacent ranges in any order are merged
+			[]Range{
+				{addr("10.0.0.4"), addr("10.0.0.7"), 1},
+				{addr("::"), addr("::1"), 1},
+				{addr("10.0.0.0"), addr("10.0.0.5"), 2},
+				{addr("10.0.0.8"), addr("10.0.0.15"), 3},
+			},
+			prefixes("10.0.0.0/28", "::/127"),
+		},
+	} {
+		cover, err := Prefixes(test.ranges...)
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, cover, "%v", test.ranges)
+	}
+
+	//everything but :: takes one prefix of every length
+	cover, err := Prefixes(Range{addr("::1"), addr("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"), 0})
+	assert.Nil(t, err)
+	assert.Len(t, cover, 128)
+
+	for i, p := range cover {
+		assert.Equal(t, 128-i, p.Bits())
+		start, _ := ones(i).next()
+		assert.Equal(t, start.addr(false), p.Addr())
+	}
+}
+
+// minimalCover returns the fewest aligned blocks tiling the addresses set in
+// covered, by splitting every block that is only partly set.
+func minimalCover(covered []bool) int {
+	set := 0
+
+	for _, c := range covered {
+		if c {
+			set++
+		}
+	}
+
+	switch set {
+	case 0:
+		return 0
+	case len(covered):
+		return 1
+	default:
+		return minimalCover(covered[:len(covered)/2]) + minimalCover(covered[len(covered)/2:])
+	}
+}
+
+func TestPrefixesRandom(t *testing.T) {
+	for name, newImplementation := range indexset.Implementations() {
+		t.Run(name, func(t *testing.T) {
+			random := rand.New(rand.NewSource(1))
+
+			for round := 0; round < 20; round++ {
+				s := New(newImplementation)
+				weights := [256]int64{}
+
+				for i := 0; i < 8; i++ {
+					left := random.Intn(256)
+					right := left + random.Intn(256-left)
+					weight := random.Int63n(5) - 2
+
+					//the same last byte in both families, across the 64-bit boundary for IPv6
+					assert.Nil(t, s.AddRange(netip.AddrFrom4([4]byte{10, 0, 0, byte(left)}), netip.AddrFrom4([4]byte{10, 0, 0, byte(right)}), weight))
+					assert.Nil(t, s.AddRange(v6(left), v6(right), weight))
+
+					for j := left; j <= right; j++ {
+						weights[j] += weight
+					}
+				}
+
+				covered := make([]bool, 256)
+
+				for i, weight := range weights {
+					covered[i] = weight != 0
+					assert.Equal(t, weight, s.At(netip.AddrFrom4([4]byte{10, 0, 0, byte(i)})))
+					assert.Equal(t, weight, s.At(v6(i)))
+				}
+
+				cover, err := s.Prefixes()
+				assert.Nil(t, err)
+				//the IPv6 addresses cannot share a prefix across the boundary
+				assert.Len(t, cover, minimalCover(covered)+minimalCover(covered[:128])+minimalCover(covered[128:]))
+
+				for i, c := range covered {
+					matches := 0
+
+					for _, p := range cover {
+						if p.Contains(netip.AddrFrom4([4]byte{10, 0, 0, byte(i)})) || p.Contains(v6(i)) {
+							matches++
+						}
+					}
+
+					if c {
+						assert.Equal(t, 2, matches, "%d %v", i, cover)
+					} else {
+						assert.Equal(t, 0, matches, "%d", i)
+					}
+				}
+			}
+		})
+	}
+}
+
+// v6 returns the i-th of 256 IPv6 addresses, half on either side of a
+// boundary between rows.
+func v6(i int) netip.Addr {
+	return uint128{hi: 1, lo: uint64(i)}.sub(uint128{lo: 128}).addr(false)
+}
@@ -0,0 +1,96 @@
+package indexsetnetip
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"net/netip"
+)
+
+// uint128 is an address of either family as an unsigned number. IPv4
+// addresses only use the low 32 bits.
+type uint128 struct {
+	hi uint64
+	lo uint64
+}
+
+func fromAddr(addr netip.Addr) uint128 {
+	if addr.Is4() {
+		b := addr.As4()
+		return uint128{lo: uint64(binary.BigEndian.Uint32(b[:]))}
+	}
+
+	b := addr.As16()
+	return uint128{hi: binary.BigEndian.Uint64(b[:8]), lo: binary.BigEndian.Uint64(b[8:])}
+}
+
+func (u uint128) addr(is4 bool) netip.Addr {
+	if is4 {
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], uint32(u.lo))
+		return netip.AddrFrom4(b)
+	}
+
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], u.hi)
+	binary.BigEndian.PutUint64(b[8:], u.lo)
+	return netip.AddrFrom16(b)
+}
+
+func (u uint128) less(v uint128) bool {
+	return u.hi < v.hi || (u.hi == v.hi && u.lo < v.lo)
+}
+
+func (u uint128) or(v uint128) uint128 {
+	return uint128{u.hi | v.hi, u.lo | v.lo}
+}
+
+func (u uint128) sub(v uint128) uint128 {
+	lo, borrow := bits.Sub64(u.lo, v.lo, 0)
+	hi, _ := bits.Sub64(u.hi, v.hi, borrow)
+
+	return uint128{hi, lo}
+}
+
+// next returns u+1, and false if that wraps around to 0.
+func (u uint128) next() (uint128, bool) {
+	lo, carry := bits.Add64(u.lo, 1, 0)
+	hi, carry := bits.Add64(u.hi, 0, carry)
+
+	return uint128{hi, lo}, carry == 0
+}
+
+func (u uint128) trailingZeros() int {
+	if u.lo != 0 {
+		return bits.TrailingZeros64(u.lo)
+	}
+
+	return 64 + bits.TrailingZeros64(u.hi)
+}
+
+func (u uint128) bitLen() int {
+	if u.hi != 0 {
+		return 64 + bits.Len64(u.hi)
+	}
+
+	return bits.Len64(u.lo)
+}
+
+// ones returns 2^n-1.
+func ones(n int) uint128 {
+	if n >= 64 {
+		return uint128{hi: 1<<(n-64) - 1, lo: math.MaxUint64}
+	}
+
+	return uint128{lo: 1<<n - 1}
+}
+
+// coordinate maps a half of an address onto the int64 line, keeping the
+// order: 0 goes to math.MinInt64 and math.MaxUint64 to math.MaxInt64.
+func coordinate(half uint64) int64 {
+	return int64(half ^ 1<<63)
+}
+
+func half(coordinate int64) uint64 {
+	return uint64(coordinate) ^ 1<<63
+}
@@ -0,0 +1,259 @@
+// Package indexsetschedule books capacity over time on an indexset.Set.
+//
+// Times are mapped to ticks of Options.Resolution counted from
+// Options.Epoch, and a reservation of [start, end) adds its capacity to
+// every tick it touches. The weight at a tick is the capacity reserved
+// during it.
+package indexsetschedule
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/friedenberg/indexset"
+)
+
+var (
+	// ErrOutOfRange is returned for times before the epoch or too far past
+	// it to be counted in ticks.
+	ErrOutOfRange = errors.New("time out of range")
+	// ErrOverCapacity is returned by Reserve when the reservation would take
+	// usage past the limit.
+	ErrOverCapacity = errors.New("over capacity")
+	// ErrNoFit is returned by FirstFit when no window can take the demand.
+	ErrNoFit = errors.New("no window fits")
+)
+
+type Options struct {
+	// Epoch is tick 0. Nothing can be reserved before it.
+	Epoch time.Time
+	// Resolution is the length of a tick, time.Second by default.
+	Resolution time.Duration
+	// Limit is the capacity available at every tick, or 0 for no limit.
+	Limit int64
+}
+
+// Schedule tracks the capacity reserved over time. It is not safe for
+// concurrent use.
+type Schedule struct {
+	set     *indexset.Set
+	options Options
+}
+
+// New returns an empty Schedule backed by newImplementation.
+func New(newImplementation func() indexset.Implementation, options Options) (*Schedule, error) {
+	if options.Resolution == 0 {
+		options.Resolution = time.Second
+	}
+
+	if options.Resolution < 0 {
+		return nil, fmt.Errorf("%w: resolution %s", indexset.ErrInvalidRange, options.Resolution)
+	}
+
+	if options.Limit < 0 {
+		return nil, fmt.Errorf("%w: limit %d", indexset.ErrInvalidRange, options.Limit)
+	}
+
+	return &Schedule{set: &indexset.Set{Implementation: newImplementation()}, options: options}, nil
+}
+
+// Set returns the Set holding the usage per tick.
+func (s *Schedule) Set() *indexset.Set {
+	return s.set
+}
+
+// tick returns the tick t falls in, or the first tick starting at or after t
+// if roundUp is set.
+func (s *Schedule) tick(t time.Time, roundUp bool) (int64, error) {
+	elapsed := t.Sub(s.options.Epoch)
+
+	//Sub saturates, so a result that does not lead back to t was clamped
+	if elapsed < 0 || !s.options.Epoch.Add(elapsed).Equal(t) {
+		return 0, fmt.Errorf("%w: %s", ErrOutOfRange, t)
+	}
+
+	tick := int64(elapsed / s.options.Resolution)
+
+	if roundUp && elapsed%s.options.Resolution != 0 {
+		tick++
+	}
+
+	return tick, nil
+}
+
+// time returns the start of tick.
+func (s *Schedule) time(tick int64) (time.Time, error) {
+	if tick > math.MaxInt64/int64(s.options.Resolution) {
+		return time.Time{}, fmt.Errorf("%w: tick %d", ErrOutOfRange, tick)
+	}
+
+	return s.options.Epoch.Add(time.Duration(tick) * s.options.Resolution), nil
+}
+
+// window returns the first and last ticks that [start, end) touches.
+func (s *Schedule) window(start, end time.Time) (left, right int64, err error) {
+	if !end.After(start) {
+		return 0, 0, fmt.Errorf("%w: %s is not after %s", indexset.ErrInvalidRange, end, start)
+	}
+
+	if left, err = s.tick(start, false); err != nil {
+		return 0, 0, err
+	}
+
+	if right, err = s.tick(end, true); err != nil {
+		return 0, 0, err
+	}
+
+	return left, right - 1, nil
+}
+
+// Reserve books capacity for [start, end). With a Limit, it fails with
+// ErrOverCapacity if that would take the usage of any tick past it.
+func (s *Schedule) Reserve(start, end time.Time, capacity int64) error {
+	if capacity <= 0 {
+		return fmt.Errorf("%w: capacity %d", indexset.ErrInvalidRange, capacity)
+	}
+
+	left, right, err := s.window(start, end)
+
+	if err != nil {
+		return err
+	}
+
+	if s.options.Limit > 0 {
+		peak, err := s.peak(left, right)
+
+		if err != nil {
+			return err
+		}
+
+		if peak > s.options.Limit-capacity {
+			return fmt.Errorf("%w: %d reserved, %d more requested, limit %d", ErrOverCapacity, peak, capacity, s.options.Limit)
+		}
+	}
+
+	r, err := indexset.MakeRange(left, right, capacity)
+
+	if err != nil {
+		return err
+	}
+
+	return s.set.Add(*r)
+}
+
+// Release returns capacity booked for [start, end) by Reserve.
+func (s *Schedule) Release(start, end time.Time, capacity int64) error {
+	if capacity <= 0 {
+		return fmt.Errorf("%w: capacity %d", indexset.ErrInvalidRange, capacity)
+	}
+
+	left, right, err := s.window(start, end)
+
+	if err != nil {
+		return err
+	}
+
+	r, err := indexset.MakeRange(left, right, capacity)
+
+	if err != nil {
+		return err
+	}
+
+	return s.set.Subtract(*r)
+}
+
+// PeakUsage returns the largest capacity reserved at any tick that
+// [start, end) touches.
+func (s *Schedule) PeakUsage(start, end time.Time) (int64, error) {
+	left, right, err := s.window(start, end)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return s.peak(left, right)
+}
+
+func (s *Schedule) peak(left, right int64) (int64, error) {
+	window, err := indexset.MakeRange(left, right, 0)
+
+	if err != nil {
+		return 0, err
+	}
+
+	peak := int64(0)
+
+	for _, m := range s.set.FindOverlapping(*window) {
+		if weight := m.IndexRange().Weight(); weight > peak {
+			peak = weight
+		}
+	}
+
+	return peak, nil
+}
+
+// FirstFit returns the earliest start, from the epoch on, of a window of
+// duration in which the capacity reserved plus capacity stays within the
+// Limit at every tick.
+func (s *Schedule) FirstFit(duration time.Duration, capacity int64) (time.Time, error) {
+	return s.FirstFitAfter(s.options.Epoch, duration, capacity)
+}
+
+// FirstFitAfter is FirstFit for windows starting at or after notBefore.
+// Windows start on a tick.
+func (s *Schedule) FirstFitAfter(notBefore time.Time, duration time.Duration, capacity int64) (time.Time, error) {
+	if duration <= 0 || capacity <= 0 {
+		return time.Time{}, fmt.Errorf("%w: duration %s, capacity %d", indexset.ErrInvalidRange, duration, capacity)
+	}
+
+	start, err := s.tick(notBefore, true)
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if s.options.Limit == 0 {
+		return s.time(start)
+	}
+
+	if capacity > s.options.Limit {
+		return time.Time{}, fmt.Errorf("%w: %d is more than the limit of %d", ErrNoFit, capacity, s.options.Limit)
+	}
+
+	ticks := int64(duration / s.options.Resolution)
+
+	if duration%s.options.Resolution != 0 {
+		ticks++
+	}
+
+	//ticks with this much reserved or more cannot also take capacity
+	blocked, err := s.set.Above(s.options.Limit - capacity + 1)
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, b := range blocked {
+		if b.Right() < start {
+			continue
+		}
+
+		if b.Left()-start >= ticks {
+			break
+		}
+
+		if b.Right() == math.MaxInt64 {
+			return time.Time{}, fmt.Errorf("%w: reserved to the end of time", ErrNoFit)
+		}
+
+		start = b.Right() + 1
+	}
+
+	if start > math.MaxInt64-(ticks-1) {
+		return time.Time{}, fmt.Errorf("%w: no room for %s", ErrNoFit, duration)
+	}
+
+	return s.time(start)
+}
@@ -0,0 +1,127 @@
+package indexsetschedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/friedenberg/indexset"
+	"github.com/stretchr/testify/assert"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func at(hours float64) time.Time {
+	return epoch.Add(time.Duration(hours * float64(time.Hour)))
+}
+
+func newTestSchedule(t *testing.T, newImplementation func() indexset.Implementation, limit int64) *Schedule {
+	s, err := New(newImplementation, Options{Epoch: epoch, Resolution: time.Minute, Limit: limit})
+	assert.Nil(t, err)
+	return s
+}
+
+func TestSchedule(t *testing.T) {
+	for name, newImplementation := range indexset.Implementations() {
+		t.Run(name, func(t *testing.T) {
+			s := newTestSchedule(t, newImplementation, 10)
+
+			assert.Nil(t, s.Reserve(at(1), at(3), 4))
+			assert.Nil(t, s.Reserve(at(2), at(4), 5))
+			assert.Nil(t, s.Reserve(at(6), at(7), 10))
+
+			peak, err := s.PeakUsage(at(0), at(24))
+			assert.Nil(t, err)
+			assert.Equal(t, int64(10), peak)
+
+			peak, err = s.PeakUsage(at(0), at(2))
+			assert.Nil(t, err)
+			assert.Equal(t, int64(4), peak)
+
+			peak, err = s.PeakUsage(at(2.5), at(3.5))
+			assert.Nil(t, err)
+			assert.Equal(t, int64(9), peak)
+
+			//usage is 4 in [1, 2), 9 in [2, 3), 5 in [3, 4) and 10 in [6, 7)
+			assert.True(t, errors.Is(s.Reserve(at(2.5), at(2.75), 2), ErrOverCapacity))
+
+			for _, test := range []struct {
+				notBefore time.Time
+				duration  time.Duration
+				capacity  int64
+				expected  time.Time
+			}{
+				{epoch, time.Hour, 2, at(0)},
+				{epoch, 2 * time.Hour, 2, at(0)},
+				{epoch, 2 * time.Hour, 6, at(0)},
+				{epoch, 2 * time.Hour, 7, at(4)},
+				{at(1), time.Hour, 1, at(1)},
+				{at(1), time.Hour, 6, at(1)},
+				{at(1), time.Hour, 7, at(4)},
+				{at(4), 3 * time.Hour, 1, at(7)},
+				{at(4).Add(time.Second), time.Hour, 10, at(4).Add(time.Minute)},
+				{at(0.5), 90 * time.Second, 7, at(0.5)},
+				{at(0.99), 90 * time.Second, 7, at(4)},
+			} {
+				start, err := s.FirstFitAfter(test.notBefore, test.duration, test.capacity)
+				assert.Nil(t, err)
+				assert.Equal(t, test.expected, start, "%s %s %d", test.notBefore, test.duration, test.capacity)
+			}
+
+			start, err := s.FirstFit(2*time.Hour, 7)
+			assert.Nil(t, err)
+			assert.Equal(t, at(4), start)
+
+			assert.Nil(t, s.Release(at(2), at(4), 5))
+
+			start, err = s.FirstFit(2*time.Hour, 7)
+			assert.Nil(t, err)
+			assert.Equal(t, at(3), start)
+		})
+	}
+}
+
+func TestScheduleRounding(t *testing.T) {
+	s := newTestSchedule(t, indexset.NewPersistentTree, 0)
+
+	//a reservation covers every minute it touches
+	assert.Nil(t, s.Reserve(epoch.Add(90*time.Second), epoch.Add(150*time.Second), 1))
+
+	ranges := make([][3]int64, 0)
+
+	s.Set().Do(
+		func(m indexset.Member) bool {
+			r := m.IndexRange()
+			ranges = append(ranges, [3]int64{r.Left(), r.Right(), r.Weight()})
+			return false
+		},
+	)
+
+	assert.Equal(t, [][3]int64{{1, 2, 1}}, ranges)
+
+	start, err := s.FirstFitAfter(epoch.Add(90*time.Second), time.Hour, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, epoch.Add(2*time.Minute), start)
+}
+
+func TestScheduleErrors(t *testing.T) {
+	s := newTestSchedule(t, indexset.NewPersistentTree, 5)
+
+	assert.True(t, errors.Is(s.Reserve(at(-1), at(1), 1), ErrOutOfRange))
+	assert.True(t, errors.Is(s.Reserve(at(2), at(1), 1), indexset.ErrInvalidRange))
+	assert.True(t, errors.Is(s.Reserve(at(1), at(2), 0), indexset.ErrInvalidRange))
+	assert.True(t, errors.Is(s.Reserve(at(1), at(2), 6), ErrOverCapacity))
+	assert.True(t, errors.Is(s.Reserve(epoch, time.Date(2500, 1, 1, 0, 0, 0, 0, time.UTC), 1), ErrOutOfRange))
+
+	_, err := s.FirstFit(time.Hour, 6)
+	assert.True(t, errors.Is(err, ErrNoFit))
+
+	_, err = s.FirstFit(0, 1)
+	assert.True(t, errors.Is(err, indexset.ErrInvalidRange))
+
+	_, err = s.PeakUsage(at(1), at(1))
+	assert.True(t, errors.Is(err, indexset.ErrInvalidRange))
+
+	_, err = New(indexset.NewPersistentTree, Options{Limit: -1})
+	assert.True(t, errors.Is(err, indexset.ErrInvalidRange))
+}
@@ -0,0 +1,364 @@
+// Package indexsettest provides a conformance suite for authors of
+// indexset.Implementation backends. Backends outside package indexset take
+// and return indexset.Range values; slice_test.go has a complete example.
+package indexsettest
+
+import (
+	"testing"
+
+	"github.com/friedenberg/indexset"
+	"github.com/stretchr/testify/assert"
+)
+
+// Segment is a range written as {left, right, weight}.
+type Segment = [3]int64
+
+type addTestCase struct {
+	description string
+	added       []Segment
+	expected    []Segment
+	expectedMax int64
+}
+
+func addTestCases() []addTestCase {
+	return []addTestCase{
+		{
+			description: "empty",
+			expected:    []Segment{},
+		},
+		{
+			description: "one range",
+			added:       []Segment{{1, 5, 1}},
+			expected:    []Segment{{1, 5, 1}},
+			expectedMax: 1,
+		},
+		{
+			description: "disjoint, unordered",
+			added:       []Segment{{10, 12, 1}, {1, 2, 3}, {5, 6, 2}},
+			expected:    []Segment{{1, 2, 3}, {5, 6, 2}, {10, 12, 1}},
+			expectedMax: 3,
+		},
+		{
+			description: "touching",
+			added:       []Segment{{6, 8, 1}, {1, 5, 1}},
+			expected:    []Segment{{1, 5, 1}, {6, 8, 1}},
+			expectedMax: 1,
+		},
+		{
+			description: "overlap same",
+			added:       []Segment{{1, 5, 1}, {1, 5, 1}, {1, 5, 1}},
+			expected:    []Segment{{1, 5, 3}},
+			expectedMax: 3,
+		},
+		{
+			description: "inside",
+			added:       []Segment{{1, 10, 1}, {3, 5, 2}},
+			expected:    []Segment{{1, 2, 1}, {3, 5, 3}, {6, 10, 1}},
+			expectedMax: 3,
+		},
+		{
+			description: "inside left",
+			added:       []Segment{{1, 10, 1}, {1, 3, 2}},
+			expected:    []Segment{{1, 3, 3}, {4, 10, 1}},
+			expectedMax: 3,
+		},
+		{
+			description: "inside right",
+			added:       []Segment{{1, 10, 1}, {5, 10, 2}},
+			expected:    []Segment{{1, 4, 1}, {5, 10, 3}},
+			expectedMax: 3,
+		},
+		{
+			description: "outside right",
+			added:       []Segment{{1, 5, 1}, {4, 8, 2}},
+			expected:    []Segment{{1, 3, 1}, {4, 5, 3}, {6, 8, 2}},
+			expectedMax: 3,
+		},
+		{
+			description: "outside left",
+			added:       []Segment{{4, 8, 2}, {1, 5, 1}},
+			expected:    []Segment{{1, 3, 1}, {4, 5, 3}, {6, 8, 2}},
+			expectedMax: 3,
+		},
+		{
+			description: "containing",
+			added:       []Segment{{3, 4, 2}, {1, 5, 1}},
+			expected:    []Segment{{1, 2, 1}, {3, 4, 3}, {5, 5, 1}},
+			expectedMax: 3,
+		},
+		{
+			description: "spanning gaps",
+			added:       []Segment{{1, 2, 1}, {5, 6, 1}, {0, 10, 1}},
+			expected:    []Segment{{0, 0, 1}, {1, 2, 2}, {3, 4, 1}, {5, 6, 2}, {7, 10, 1}},
+			expectedMax: 2,
+		},
+		{
+			description: "staircase",
+			added:       []Segment{{1, 5, 1}, {2, 6, 1}, {3, 7, 1}},
+			expected:    []Segment{{1, 1, 1}, {2, 2, 2}, {3, 5, 3}, {6, 6, 2}, {7, 7, 1}},
+			expectedMax: 3,
+		},
+	}
+}
+
+type replaceTestCase struct {
+	description string
+	added       []Segment
+	nth         int
+	replacement []Segment
+	expected    []Segment
+}
+
+func replaceTestCases() []replaceTestCase {
+	return []replaceTestCase{
+		{
+			description: "one",
+			added:       []Segment{{1, 5, 1}, {6, 8, 1}},
+			nth:         0,
+			replacement: []Segment{{2, 5, 1}},
+			expected:    []Segment{{2, 5, 1}, {6, 8, 1}},
+		},
+		{
+			description: "many",
+			added:       []Segment{{1, 5, 1}, {6, 8, 1}},
+			nth:         0,
+			replacement: []Segment{{1, 1, 2}, {2, 5, 1}},
+			expected:    []Segment{{1, 1, 2}, {2, 5, 1}, {6, 8, 1}},
+		},
+		{
+			description: "many, last",
+			added:       []Segment{{1, 5, 1}, {6, 8, 1}},
+			nth:         1,
+			replacement: []Segment{{6, 6, 1}, {7, 8, 3}},
+			expected:    []Segment{{1, 5, 1}, {6, 6, 1}, {7, 8, 3}},
+		},
+		{
+			description: "remove only",
+			added:       []Segment{{1, 5, 1}},
+			nth:         0,
+			expected:    []Segment{},
+		},
+		{
+			description: "remove first",
+			added:       []Segment{{1, 5, 1}, {6, 8, 1}, {10, 12, 1}},
+			nth:         0,
+			expected:    []Segment{{6, 8, 1}, {10, 12, 1}},
+		},
+		{
+			description: "remove middle",
+			added:       []Segment{{1, 5, 1}, {6, 8, 1}, {10, 12, 1}},
+			nth:         1,
+			expected:    []Segment{{1, 5, 1}, {10, 12, 1}},
+		},
+		{
+			description: "remove last",
+			added:       []Segment{{1, 5, 1}, {6, 8, 1}, {10, 12, 1}},
+			nth:         2,
+			expected:    []Segment{{1, 5, 1}, {6, 8, 1}},
+		},
+	}
+}
+
+type findOverlappingTestCase struct {
+	description string
+	query       Segment
+	expected    []Segment
+}
+
+func findOverlappingTestCases() []findOverlappingTestCase {
+	return []findOverlappingTestCase{
+		{"before", Segment{0, 0, 1}, []Segment{}},
+		{"between", Segment{6, 9, 1}, []Segment{}},
+		{"after", Segment{20, 30, 1}, []Segment{}},
+		{"first", Segment{2, 3, 1}, []Segment{{1, 5, 1}}},
+		{"edge", Segment{5, 5, 1}, []Segment{{1, 5, 1}}},
+		{"last", Segment{12, 40, 1}, []Segment{{10, 12, 2}}},
+		{"spanning", Segment{4, 10, 1}, []Segment{{1, 5, 1}, {10, 12, 2}}},
+		{"all", Segment{0, 100, 1}, []Segment{{1, 5, 1}, {10, 12, 2}}},
+	}
+}
+
+// RunConformance runs the suite against fresh Implementations returned by
+// newImplementation. Every subtest also checks that Validate succeeds after
+// each mutation.
+func RunConformance(t *testing.T, newImplementation func() indexset.Implementation) {
+	t.Run("Add", func(t *testing.T) { testAdd(t, newImplementation) })
+	t.Run("AddOrFindOverlapping", func(t *testing.T) { testAddOrFindOverlapping(t, newImplementation) })
+	t.Run("Replace", func(t *testing.T) { testReplace(t, newImplementation) })
+	t.Run("FindOverlapping", func(t *testing.T) { testFindOverlapping(t, newImplementation) })
+	t.Run("Do", func(t *testing.T) { testDo(t, newImplementation) })
+}
+
+func makeSet(t *testing.T, newImplementation func() indexset.Implementation, added ...Segment) *indexset.Set {
+	s := &indexset.Set{Implementation: newImplementation()}
+
+	for _, segment := range added {
+		r, err := indexset.MakeRange(segment[0], segment[1], segment[2])
+
+		if !assert.Nil(t, err) {
+			continue
+		}
+
+		assert.Nil(t, s.Add(*r), "add %v", segment)
+		assert.Nil(t, s.Validate(), "validate after adding %v", segment)
+	}
+
+	return s
+}
+
+// Segments returns every segment of s in iteration order.
+func Segments(t *testing.T, s *indexset.Set) []Segment {
+	segments := make([]Segment, 0)
+
+	err := s.Do(
+		func(m indexset.Member) bool {
+			segments = append(segments, toSegment(m))
+			return false
+		},
+	)
+
+	assert.Nil(t, err)
+
+	return segments
+}
+
+func toSegment(m indexset.Member) Segment {
+	r := m.IndexRange()
+	return Segment{r.Left(), r.Right(), r.Weight()}
+}
+
+func toSegments(members []indexset.Member) []Segment {
+	segments := make([]Segment, len(members))
+
+	for i, m := range members {
+		segments[i] = toSegment(m)
+	}
+
+	return segments
+}
+
+func testAdd(t *testing.T, newImplementation func() indexset.Implementation) {
+	for _, test := range addTestCases() {
+		t.Run(
+			test.description,
+			func(t *testing.T) {
+				s := makeSet(t, newImplementation, test.added...)
+
+				assert.Equal(t, test.expected, Segments(t, s))
+				assert.Equal(t, test.expectedMax, s.Max())
+
+				for i, segment := range test.expected {
+					nth := s.Nth(i)
+
+					if assert.NotNil(t, nth) {
+						assert.Equal(t, segment, toSegment(nth))
+					}
+				}
+
+				assert.Nil(t, s.Nth(len(test.expected)))
+			},
+		)
+	}
+}
+
+func testAddOrFindOverlapping(t *testing.T, newImplementation func() indexset.Implementation) {
+	s := makeSet(t, newImplementation, Segment{1, 5, 1}, Segment{10, 12, 2})
+
+	r, err := indexset.MakeRange(4, 10, 7)
+	assert.Nil(t, err)
+
+	overlapping, err := s.AddOrFindOverlapping(*r)
+	assert.Nil(t, err)
+	assert.Equal(t, []Segment{{1, 5, 1}, {10, 12, 2}}, toSegments(overlapping))
+	assert.Equal(t, []Segment{{1, 5, 1}, {10, 12, 2}}, Segments(t, s), "overlapping range must not be inserted")
+
+	r, err = indexset.MakeRange(7, 8, 3)
+	assert.Nil(t, err)
+
+	overlapping, err = s.AddOrFindOverlapping(*r)
+	assert.Nil(t, err)
+	assert.Empty(t, overlapping)
+	assert.Equal(t, []Segment{{1, 5, 1}, {7, 8, 3}, {10, 12, 2}}, Segments(t, s))
+	assert.Nil(t, s.Validate())
+}
+
+func testReplace(t *testing.T, newImplementation func() indexset.Implementation) {
+	for _, test := range replaceTestCases() {
+		t.Run(
+			test.description,
+			func(t *testing.T) {
+				s := makeSet(t, newImplementation, test.added...)
+
+				ranges, err := indexset.MakeRanges(test.replacement...)
+				assert.Nil(t, err)
+
+				nth := s.Nth(test.nth)
+
+				if !assert.NotNil(t, nth) {
+					return
+				}
+
+				assert.Nil(t, s.Replace(nth, ranges...))
+				assert.Nil(t, s.Validate())
+				assert.Equal(t, test.expected, Segments(t, s))
+			},
+		)
+	}
+}
+
+func testFindOverlapping(t *testing.T, newImplementation func() indexset.Implementation) {
+	for _, test := range findOverlappingTestCases() {
+		t.Run(
+			test.description,
+			func(t *testing.T) {
+				s := makeSet(t, newImplementation, Segment{1, 5, 1}, Segment{10, 12, 2})
+
+				r, err := indexset.MakeRange(test.query[0], test.query[1], test.query[2])
+				assert.Nil(t, err)
+
+				assert.Equal(t, test.expected, toSegments(s.FindOverlapping(*r)))
+			},
+		)
+	}
+
+	t.Run(
+		"empty",
+		func(t *testing.T) {
+			s := makeSet(t, newImplementation)
+
+			r, err := indexset.MakeRange(0, 100, 1)
+			assert.Nil(t, err)
+
+			assert.Empty(t, s.FindOverlapping(*r))
+		},
+	)
+}
+
+func testDo(t *testing.T, newImplementation func() indexset.Implementation) {
+	s := makeSet(t, newImplementation, Segment{20, 21, 1}, Segment{1, 5, 1}, Segment{10, 12, 2})
+
+	visited := make([]Segment, 0)
+
+	err := s.Do(
+		func(m indexset.Member) bool {
+			visited = append(visited, toSegment(m))
+			return len(visited) == 2
+		},
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []Segment{{1, 5, 1}, {10, 12, 2}}, visited, "Do must stop when f returns true")
+
+	empty := makeSet(t, newImplementation)
+
+	assert.Nil(
+		t,
+		empty.Do(
+			func(m indexset.Member) bool {
+				t.Errorf("unexpected member %v", toSegment(m))
+				return false
+			},
+		),
+	)
+	assert.Nil(t, empty.Validate())
+}
@@ -0,0 +1,89 @@
+package indexsettest_test
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/friedenberg/indexset"
+	"github.com/friedenberg/indexset/indexsettest"
+)
+
+// sliceImplementation is an Implementation written outside package indexset,
+// keeping its segments in a sorted slice.
+type sliceImplementation struct {
+	ranges []indexset.Range
+}
+
+type sliceMember indexset.Range
+
+func (m sliceMember) IndexRange() indexset.Range {
+	return indexset.Range(m)
+}
+
+func overlaps(a, b indexset.Range) bool {
+	return a.Left() <= b.Right() && b.Left() <= a.Right()
+}
+
+func (s *sliceImplementation) FindOverlapping(overlap indexset.Range) []indexset.Member {
+	overlapping := make([]indexset.Member, 0)
+
+	for _, r := range s.ranges {
+		if overlaps(r, overlap) {
+			overlapping = append(overlapping, sliceMember(r))
+		}
+	}
+
+	return overlapping
+}
+
+func (s *sliceImplementation) Replace(original indexset.Member, replacements ...indexset.Range) error {
+	for i, r := range s.ranges {
+		if r == original.IndexRange() {
+			tail := append(replacements[:len(replacements):len(replacements)], s.ranges[i+1:]...)
+			s.ranges = append(s.ranges[:i], tail...)
+			return nil
+		}
+	}
+
+	return indexset.ErrMemberNotFound
+}
+
+func (s *sliceImplementation) AddOrFindOverlapping(newRange indexset.Range) ([]indexset.Member, error) {
+	if overlapping := s.FindOverlapping(newRange); len(overlapping) > 0 {
+		return overlapping, nil
+	}
+
+	i := sort.Search(len(s.ranges), func(i int) bool { return s.ranges[i].Left() > newRange.Right() })
+	s.ranges = append(s.ranges[:i], append([]indexset.Range{newRange}, s.ranges[i:]...)...)
+
+	return nil, nil
+}
+
+func (s *sliceImplementation) Do(f func(indexset.Member) (stop bool)) error {
+	for _, r := range s.ranges {
+		if f(sliceMember(r)) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (s *sliceImplementation) Validate() error {
+	for i, r := range s.ranges {
+		if r.Left() > r.Right() {
+			return fmt.Errorf("%w: %s", indexset.ErrEmptySegment, r)
+		}
+
+		if i > 0 && s.ranges[i-1].Right() >= r.Left() {
+			return fmt.Errorf("%w: %s after %s", indexset.ErrOutOfOrder, r, s.ranges[i-1])
+		}
+	}
+
+	return nil
+}
+
+func TestConformanceSlice(t *testing.T) {
+	indexsettest.RunConformance(t, func() indexset.Implementation { return &sliceImplementation{} })
+}
@@ -0,0 +1,99 @@
+// Package benchmark holds the Set operations measured by the indexset
+// package benchmarks and by cmd/indexset-bench.
+package benchmark
+
+import (
+	"testing"
+
+	"github.com/friedenberg/indexset"
+)
+
+// Operation measures one Set operation against ranges from a workload. Run
+// skips b when there are no ranges, since there is nothing to measure.
+type Operation struct {
+	Name string
+	Run  func(b *testing.B, newImplementation func() indexset.Implementation, ranges []indexset.Range)
+}
+
+func Operations() []Operation {
+	return []Operation{
+		{Name: "Add", Run: skipEmpty(benchmarkAdd)},
+		{Name: "Max", Run: skipEmpty(benchmarkMax)},
+		{Name: "FindOverlapping", Run: skipEmpty(benchmarkFindOverlapping)},
+		{Name: "Nth", Run: skipEmpty(benchmarkNth)},
+	}
+}
+
+func skipEmpty(run func(*testing.B, func() indexset.Implementation, []indexset.Range)) func(*testing.B, func() indexset.Implementation, []indexset.Range) {
+	return func(b *testing.B, newImplementation func() indexset.Implementation, ranges []indexset.Range) {
+		if len(ranges) == 0 {
+			b.Skip("empty workload")
+		}
+
+		run(b, newImplementation, ranges)
+	}
+}
+
+func benchmarkSet(b *testing.B, newImplementation func() indexset.Implementation, ranges []indexset.Range) *indexset.Set {
+	s := &indexset.Set{Implementation: newImplementation()}
+
+	for _, r := range ranges {
+		if err := s.Add(r); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	return s
+}
+
+// benchmarkAdd reports the cost of a single Add, amortized over building a
+// set from every range in the workload.
+func benchmarkAdd(b *testing.B, newImplementation func() indexset.Implementation, ranges []indexset.Range) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; {
+		s := &indexset.Set{Implementation: newImplementation()}
+
+		for _, r := range ranges {
+			if i == b.N {
+				break
+			}
+
+			if err := s.Add(r); err != nil {
+				b.Fatal(err)
+			}
+
+			i++
+		}
+	}
+}
+
+func benchmarkMax(b *testing.B, newImplementation func() indexset.Implementation, ranges []indexset.Range) {
+	s := benchmarkSet(b, newImplementation, ranges)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		s.Max()
+	}
+}
+
+func benchmarkFindOverlapping(b *testing.B, newImplementation func() indexset.Implementation, ranges []indexset.Range) {
+	s := benchmarkSet(b, newImplementation, ranges)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		s.FindOverlapping(ranges[i%len(ranges)])
+	}
+}
+
+func benchmarkNth(b *testing.B, newImplementation func() indexset.Implementation, ranges []indexset.Range) {
+	s := benchmarkSet(b, newImplementation, ranges)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		s.Nth(i % len(ranges))
+	}
+}
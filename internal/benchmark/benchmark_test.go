@@ -0,0 +1,45 @@
+package benchmark
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/friedenberg/indexset"
+	"github.com/stretchr/testify/assert"
+)
+
+const benchmarkWorkloadSize = 1000
+
+func BenchmarkSet(b *testing.B) {
+	for _, operation := range Operations() {
+		for _, workload := range indexset.Workloads() {
+			ranges := indexset.GenerateWorkload(workload, benchmarkWorkloadSize, 1)
+
+			for implementationName, implementation := range indexset.Implementations() {
+				b.Run(
+					fmt.Sprintf("%s/%s/%s", operation.Name, workload, implementationName),
+					func(b *testing.B) {
+						operation.Run(b, implementation, ranges)
+					},
+				)
+			}
+		}
+	}
+}
+
+func TestEmptyWorkload(t *testing.T) {
+	for _, operation := range Operations() {
+		t.Run(
+			operation.Name,
+			func(t *testing.T) {
+				result := testing.Benchmark(
+					func(b *testing.B) {
+						operation.Run(b, indexset.NewLinkedList, nil)
+					},
+				)
+
+				assert.Equal(t, 0, result.N)
+			},
+		)
+	}
+}
@@ -1,7 +1,6 @@
 package indexset
 
 import (
-	"errors"
 	"fmt"
 )
 
@@ -17,7 +16,7 @@ func (n *node) IndexRange() indexRange {
 
 func (n *node) setPrev(prev *node) error {
 	if n.next == prev {
-		return errors.New("failed to set prev because it's a circular reference")
+		return fmt.Errorf("%w: failed to set prev", ErrCircularReference)
 	}
 
 	n.prev = prev
@@ -26,7 +25,7 @@ func (n *node) setPrev(prev *node) error {
 
 func (n *node) setNext(next *node) error {
 	if n.prev == next {
-		return errors.New("failed to set next because it's a circular reference")
+		return fmt.Errorf("%w: failed to set next", ErrCircularReference)
 	}
 
 	n.next = next
@@ -41,6 +40,13 @@ type linkedList struct {
 	head *node
 }
 
+func NewLinkedList() Implementation {
+	return &linkedList{}
+}
+
+// FindOverlapping returns the segments overlapping overlap. It walks the list
+// with Do and so stops at the first corrupted segment, returning only the
+// segments found before it.
 func (l *linkedList) FindOverlapping(overlap indexRange) []Member {
 	overlapping := make([]Member, 0)
 
@@ -62,14 +68,18 @@ func (l *linkedList) Replace(original Member, replacements ...indexRange) error
 	n, ok := original.(*node)
 
 	if !ok {
-		return errors.New("member is not an instance of node")
+		return &RangeError{Op: "replace", Ranges: []indexRange{original.IndexRange()}, Err: ErrInvalidMember}
 	}
 
 	switch len(replacements) {
 	case 0:
 		if n == l.head {
 			l.head = n.next
-			l.head.prev = nil
+			n.next = nil
+
+			if l.head != nil {
+				l.head.prev = nil
+			}
 		} else if n.next != nil {
 			prev := n.prev
 			next := n.next
@@ -121,6 +131,7 @@ func (q *linkedList) AddOrFindOverlapping(newRange indexRange) (overlapping []Me
 	}
 
 	currentNode := q.head
+	var lastNode *node
 
 	//finding overlapping index ranges
 	for {
@@ -132,23 +143,12 @@ func (q *linkedList) AddOrFindOverlapping(newRange indexRange) (overlapping []Me
 
 		switch position {
 		case comparisonPositionLeft:
+			//newRange lies entirely before currentNode, so nothing further can overlap
 			if len(overlapping) == 0 {
-				next := currentNode.next
-
-				newNode := &node{
-					indexRange: newRange,
-					prev:       currentNode,
-					next:       next,
-				}
-
-				if next != nil {
-					next.prev = newNode
-				}
-
-				currentNode.next = newNode
+				q.insertBefore(currentNode, newRange)
 			}
 
-			break
+			return overlapping, nil
 
 		case comparisonPositionOverlap:
 			overlapping = append(overlapping, currentNode)
@@ -157,28 +157,54 @@ func (q *linkedList) AddOrFindOverlapping(newRange indexRange) (overlapping []Me
 			//noop
 
 		default:
-			//todo more detail
-			err = errors.New("impossible state")
-			break
+			return overlapping, &RangeError{
+				Op:     "add",
+				Ranges: []indexRange{currentNode.indexRange, newRange},
+				Err:    ErrImpossibleState,
+			}
 		}
 
+		lastNode = currentNode
 		currentNode = currentNode.next
 	}
 
-	return overlapping, err
+	if len(overlapping) == 0 {
+		lastNode.next = &node{
+			indexRange: newRange,
+			prev:       lastNode,
+		}
+	}
+
+	return overlapping, nil
+}
+
+func (q *linkedList) insertBefore(n *node, newRange indexRange) {
+	newNode := &node{
+		indexRange: newRange,
+		prev:       n.prev,
+		next:       n,
+	}
+
+	if n.prev != nil {
+		n.prev.next = newNode
+	} else {
+		q.head = newNode
+	}
+
+	n.prev = newNode
 }
 
-func (i *linkedList) Do(f func(Member) (stop bool)) {
+func (i *linkedList) Do(f func(Member) (stop bool)) error {
 	currentNode := i.head
-	var prevNode *node
+	checker := segmentChecker{op: "do"}
 
 	for {
 		if currentNode == nil {
 			break
 		}
 
-		if prevNode != nil && (currentNode.indexRange.left < prevNode.indexRange.right || currentNode.indexRange.right < prevNode.indexRange.right) {
-			panic(fmt.Errorf("ranges out of order: prev (%s), current(%s)", prevNode.indexRange, currentNode.indexRange))
+		if err := checker.check(currentNode.indexRange); err != nil {
+			return err
 		}
 
 		stop := f(currentNode)
@@ -187,7 +213,31 @@ func (i *linkedList) Do(f func(Member) (stop bool)) {
 			break
 		}
 
-		prevNode = currentNode
 		currentNode = currentNode.next
 	}
+
+	return nil
+}
+
+func (l *linkedList) Validate() error {
+	checker := segmentChecker{op: "validate"}
+	var prevNode *node
+
+	for currentNode := l.head; currentNode != nil; currentNode = currentNode.next {
+		if currentNode.prev != prevNode {
+			return &RangeError{
+				Op:     "validate",
+				Ranges: []indexRange{currentNode.indexRange},
+				Err:    ErrBrokenLink,
+			}
+		}
+
+		if err := checker.check(currentNode.indexRange); err != nil {
+			return err
+		}
+
+		prevNode = currentNode
+	}
+
+	return nil
 }
@@ -0,0 +1,205 @@
+package indexset
+
+import (
+	"container/heap"
+	"sync"
+)
+
+type mergeCursor struct {
+	ranges []indexRange
+}
+
+type mergeHeap []*mergeCursor
+
+func (h mergeHeap) Len() int {
+	return len(h)
+}
+
+func (h mergeHeap) Less(i, j int) bool {
+	return h[i].ranges[0].lessThan(h[j].ranges[0])
+}
+
+func (h mergeHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *mergeHeap) Push(x interface{}) {
+	*h = append(*h, x.(*mergeCursor))
+}
+
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+// BuildParallel runs each builder against its own empty Set in a separate
+// goroutine, then merges the partial sets with Merge. The first builder error
+// is returned.
+func BuildParallel(newImplementation func() Implementation, builders ...func(*Set) error) (*Set, error) {
+	sets := make([]*Set, len(builders))
+	errs := make([]error, len(builders))
+
+	var wg sync.WaitGroup
+
+	for i, build := range builders {
+		sets[i] = &Set{Implementation: newImplementation()}
+		wg.Add(1)
+
+		go func(i int, build func(*Set) error) {
+			defer wg.Done()
+			errs[i] = build(sets[i])
+		}(i, build)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return Merge(newImplementation, sets...)
+}
+
+// Merge combines sets into a new Set, summing weights where their segments
+// overlap. It is MergeInto an empty Set with the default Arithmetic.
+func Merge(newImplementation func() Implementation, sets ...*Set) (*Set, error) {
+	set := &Set{Implementation: newImplementation()}
+
+	if err := MergeInto(set, sets...); err != nil {
+		return nil, err
+	}
+
+	return set, nil
+}
+
+// MergeInto combines sets into destination, which must be empty, summing
+// weights where their segments overlap with destination.Arithmetic. The
+// segments of all sets are consumed in a single k-way ordered pass, so only
+// the tail of the output that can still overlap the next segment is ever
+// split, and the ordered output is inserted with a single Replace. The
+// segments go straight to the Implementation of destination, so they are
+// not recorded by its History, Provenance or observers.
+func MergeInto(destination *Set, sets ...*Set) error {
+	empty := true
+
+	if err := destination.Do(func(Member) bool { empty = false; return true }); err != nil {
+		return err
+	}
+
+	if !empty {
+		return ErrNotEmpty
+	}
+
+	h := make(mergeHeap, 0, len(sets))
+
+	for _, s := range sets {
+		cursor := &mergeCursor{}
+
+		err := s.Do(
+			func(m Member) bool {
+				cursor.ranges = append(cursor.ranges, m.IndexRange())
+				return false
+			},
+		)
+
+		if err != nil {
+			return err
+		}
+
+		if len(cursor.ranges) > 0 {
+			h = append(h, cursor)
+		}
+	}
+
+	heap.Init(&h)
+
+	merged := make([]indexRange, 0)
+	pending := make([]indexRange, 0)
+
+	for h.Len() > 0 {
+		cursor := h[0]
+		next := cursor.ranges[0]
+		cursor.ranges = cursor.ranges[1:]
+
+		if len(cursor.ranges) == 0 {
+			heap.Pop(&h)
+		} else {
+			heap.Fix(&h, 0)
+		}
+
+		flushed := 0
+
+		for flushed < len(pending) && pending[flushed].right < next.left {
+			flushed++
+		}
+
+		merged = append(merged, pending[:flushed]...)
+
+		var err error
+
+		if pending, err = mergeInto(pending[flushed:], next, destination.Arithmetic); err != nil {
+			return err
+		}
+	}
+
+	merged = append(merged, pending...)
+
+	if len(merged) == 0 {
+		return nil
+	}
+
+	//the first segment is the only one the Implementation has to place, the
+	//rest follow it in order
+	overlapping, err := destination.AddOrFindOverlapping(merged[0])
+
+	if err != nil {
+		return err
+	}
+
+	if len(overlapping) > 0 {
+		return &RangeError{Op: "merge", Ranges: []indexRange{merged[0]}, Err: ErrImpossibleState}
+	}
+
+	first := destination.FindOverlapping(merged[0])
+
+	if len(first) != 1 {
+		return &RangeError{Op: "merge", Ranges: []indexRange{merged[0]}, Err: ErrImpossibleState}
+	}
+
+	return destination.Replace(first[0], merged...)
+}
+
+// mergeInto splits newRange into the ordered, non-overlapping segments of
+// pending, none of which end before newRange starts. Pieces whose weights sum
+// to 0 are dropped, as Add does.
+func mergeInto(pending []indexRange, newRange indexRange, arithmetic WeightArithmetic) ([]indexRange, error) {
+	end := 0
+
+	for end < len(pending) && pending[end].left <= newRange.right {
+		end++
+	}
+
+	replacements := make([]indexRange, 0, end+2)
+	carryover := newRange
+
+	for _, current := range pending[:end] {
+		var pieces []indexRange
+		var err error
+
+		if pieces, carryover, err = current.splitWith(carryover, arithmetic); err != nil {
+			return nil, err
+		}
+
+		replacements = append(replacements, withoutZeroWeights(pieces)...)
+	}
+
+	if carryover != indexRangeZero {
+		replacements = append(replacements, carryover)
+	}
+
+	return append(replacements, pending[end:]...), nil
+}
@@ -0,0 +1,162 @@
+package indexset
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mergeTestCase struct {
+	description string
+	sets        [][]indexRange
+	expected    []indexRange
+}
+
+func mergeTestCases() []mergeTestCase {
+	return []mergeTestCase{
+		{
+			description: "empty",
+			sets:        [][]indexRange{{}, {}},
+			expected:    []indexRange{},
+		},
+		{
+			description: "disjoint",
+			sets: [][]indexRange{
+				{{1, 2, 1}, {9, 10, 1}},
+				{{4, 6, 2}},
+				{{12, 15, 3}},
+			},
+			expected: []indexRange{
+				{1, 2, 1},
+				{4, 6, 2},
+				{9, 10, 1},
+				{12, 15, 3},
+			},
+		},
+		{
+			description: "inside and equal",
+			sets: [][]indexRange{
+				{{1, 10, 1}, {20, 30, 1}},
+				{{3, 5, 2}, {20, 30, 4}},
+				{{40, 50, 1}},
+			},
+			expected: []indexRange{
+				{1, 2, 1},
+				{3, 5, 3},
+				{6, 10, 1},
+				{20, 30, 5},
+				{40, 50, 1},
+			},
+		},
+		{
+			description: "weights summing to zero",
+			sets: [][]indexRange{
+				{{1, 5, 1}, {10, 12, 2}},
+				{{1, 5, -1}, {11, 15, -2}},
+			},
+			expected: []indexRange{
+				{10, 10, 2},
+				{13, 15, -2},
+			},
+		},
+	}
+}
+
+func TestMerge(t *testing.T) {
+	for implementationName, implementation := range implementationsToTest(t) {
+		for _, test := range mergeTestCases() {
+			t.Run(
+				fmt.Sprintf("%s: %s", implementationName, test.description),
+				func(t *testing.T) {
+					sets := make([]*Set, len(test.sets))
+
+					for i, ranges := range test.sets {
+						sets[i] = &Set{Implementation: implementation()}
+
+						for _, indexRange := range ranges {
+							assert.Nil(t, sets[i].Add(indexRange))
+						}
+					}
+
+					merged, err := Merge(implementation, sets...)
+					assert.Nil(t, err)
+					assert.Equal(t, test.expected, collectIndexRanges(t, merged))
+				},
+			)
+		}
+	}
+}
+
+func TestBuildParallel(t *testing.T) {
+	for implementationName, implementation := range implementationsToTest(t) {
+		for _, test := range mergeTestCases() {
+			t.Run(
+				fmt.Sprintf("%s: %s", implementationName, test.description),
+				func(t *testing.T) {
+					builders := make([]func(*Set) error, len(test.sets))
+
+					for i, ranges := range test.sets {
+						ranges := ranges
+
+						builders[i] = func(s *Set) error {
+							for _, indexRange := range ranges {
+								if err := s.Add(indexRange); err != nil {
+									return err
+								}
+							}
+
+							return nil
+						}
+					}
+
+					merged, err := BuildParallel(implementation, builders...)
+					assert.Nil(t, err)
+					assert.Equal(t, test.expected, collectIndexRanges(t, merged))
+				},
+			)
+		}
+	}
+}
+
+func TestBuildParallelError(t *testing.T) {
+	expected := errors.New("failed to read input")
+
+	_, err := BuildParallel(
+		NewPersistentTree,
+		func(s *Set) error { return s.Add(indexRange{1, 2, 1}) },
+		func(s *Set) error { return expected },
+	)
+
+	assert.Equal(t, expected, err)
+}
+
+func TestMergeInto(t *testing.T) {
+	for implementationName, implementation := range implementationsToTest(t) {
+		t.Run(
+			implementationName,
+			func(t *testing.T) {
+				a := &Set{Implementation: implementation()}
+				assert.Nil(t, a.Add(indexRange{1, 5, math.MaxInt64}))
+				b := &Set{Implementation: implementation()}
+				assert.Nil(t, b.Add(indexRange{3, 8, 1}))
+
+				_, err := Merge(implementation, a, b)
+				assert.True(t, errors.Is(err, ErrWeightOverflow))
+
+				destination := &Set{Implementation: implementation(), Arithmetic: WeightArithmeticSaturating}
+				assert.Nil(t, MergeInto(destination, a, b))
+				assert.Equal(
+					t,
+					[]indexRange{{1, 2, math.MaxInt64}, {3, 5, math.MaxInt64}, {6, 8, 1}},
+					collectIndexRanges(t, destination),
+				)
+				assert.Nil(t, destination.Validate())
+
+				assert.Equal(t, ErrNotEmpty, MergeInto(destination, a))
+			},
+		)
+	}
+}
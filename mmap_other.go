@@ -0,0 +1,20 @@
+//go:build !unix
+
+package indexset
+
+import (
+	"fmt"
+	"os"
+)
+
+func mmap(_ *os.File, _ int) ([]byte, error) {
+	return nil, fmt.Errorf("%w: mmap", ErrNotSupported)
+}
+
+func munmap(_ []byte) error {
+	return fmt.Errorf("%w: mmap", ErrNotSupported)
+}
+
+func msync(_ []byte) error {
+	return fmt.Errorf("%w: mmap", ErrNotSupported)
+}
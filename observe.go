@@ -0,0 +1,129 @@
+package indexset
+
+import (
+	"sort"
+)
+
+// Change is the effect of one mutation on a Set: the segments it took out
+// and the segments it put in, each sorted by left. A segment that a mutation
+// took out and put back unchanged appears in neither.
+type Change struct {
+	Removed  []indexRange
+	Inserted []indexRange
+}
+
+type observer struct {
+	notify func(Change)
+}
+
+// recorder journals a mutation of a Set that has observers. It is a type of
+// its own so that it is not taken for an open transaction.
+type recorder struct {
+	*journal
+}
+
+// Observe calls notify with the Change made by every later Add, Subtract,
+// Rollback, Undo and Redo on s that changes its segments, after the change
+// has been made and before the call making it returns. Adds inside a
+// transaction are reported as they are made, and rolling the transaction
+// back is reported as a Change of its own. notify may use s but must not
+// modify the Change. Calling stop ends the calls.
+func (s *Set) Observe(notify func(Change)) (stop func()) {
+	o := &observer{notify: notify}
+	s.observers = append(s.observers, o)
+
+	return func() {
+		for i, other := range s.observers {
+			if other == o {
+				s.observers = append(s.observers[:i:i], s.observers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// ObserveChannel is Observe with the Changes sent on a channel that has room
+// for buffer of them. Once it is full, mutations of s block until the
+// receiver catches up, so the receiver must not wait on s. Calling stop ends
+// the sends and closes the channel.
+func (s *Set) ObserveChannel(buffer int) (changes <-chan Change, stop func()) {
+	channel := make(chan Change, buffer)
+	stopObserving := s.Observe(func(change Change) { channel <- change })
+
+	return channel, func() {
+		stopObserving()
+		close(channel)
+	}
+}
+
+// observe runs mutate and reports what it changed to the observers of s.
+func (s *Set) observe(mutate func() error) error {
+	if len(s.observers) == 0 {
+		return mutate()
+	}
+
+	//a mutation made by another one is part of its Change
+	if _, ok := s.writer().(*recorder); ok {
+		return mutate()
+	}
+
+	r := &recorder{&journal{Implementation: s.writer()}}
+	s.journals = append(s.journals, r)
+	err := s.restoreAfter(mutate)
+
+	change := changeOf(r.entries)
+
+	if len(change.Removed) == 0 && len(change.Inserted) == 0 {
+		return err
+	}
+
+	//observers may stop observing while being notified
+	for _, o := range append([]*observer(nil), s.observers...) {
+		o.notify(change)
+	}
+
+	return err
+}
+
+func (s *Set) restoreAfter(mutate func() error) error {
+	defer s.popJournal()
+	return mutate()
+}
+
+// changeOf sums the entries of a journal into the Change they make.
+func changeOf(entries []journalEntry) Change {
+	removed := make(map[indexRange]bool)
+	inserted := make(map[indexRange]bool)
+
+	for _, entry := range entries {
+		if entry.hasRemoved {
+			if inserted[entry.removed] {
+				delete(inserted, entry.removed)
+			} else {
+				removed[entry.removed] = true
+			}
+		}
+
+		for _, r := range entry.inserted {
+			if removed[r] {
+				delete(removed, r)
+			} else {
+				inserted[r] = true
+			}
+		}
+	}
+
+	return Change{Removed: sortedRanges(removed), Inserted: sortedRanges(inserted)}
+}
+
+func sortedRanges(set map[indexRange]bool) []indexRange {
+	ranges := make([]indexRange, 0, len(set))
+
+	for r := range set {
+		ranges = append(ranges, r)
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].left < ranges[j].left })
+
+	return ranges
+}
@@ -0,0 +1,165 @@
+package indexset
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObserve(t *testing.T) {
+	for name, newImplementation := range implementationsToTest(t) {
+		t.Run(name, func(t *testing.T) {
+			s := &Set{Implementation: newImplementation(), History: &History{}}
+			changes := make([]Change, 0)
+			stop := s.Observe(func(change Change) { changes = append(changes, change) })
+
+			assert.Nil(t, s.Add(indexRange{1, 5, 1}))
+			assert.Nil(t, s.Add(indexRange{3, 8, 2}))
+			assert.Nil(t, s.Subtract(indexRange{1, 2, 1}))
+
+			//failed Adds change nothing and are not reported
+			assert.NotNil(t, s.Add(indexRange{4, 4, math.MaxInt64}))
+			assert.Nil(t, s.Add(indexRange{0, 0, 0}))
+
+			assert.Nil(t, s.Undo())
+			assert.Nil(t, s.Redo())
+
+			tx := s.Begin()
+			assert.Nil(t, tx.Add(indexRange{20, 20, 1}))
+			assert.Nil(t, tx.Rollback())
+
+			stop()
+			assert.Nil(t, s.Add(indexRange{30, 30, 1}))
+
+			expected := []Change{
+				{Removed: []indexRange{}, Inserted: []indexRange{{1, 5, 1}}},
+				{
+					Removed:  []indexRange{{1, 5, 1}},
+					Inserted: []indexRange{{1, 2, 1}, {3, 5, 3}, {6, 8, 2}},
+				},
+				{Removed: []indexRange{{1, 2, 1}}, Inserted: []indexRange{}},
+				{Removed: []indexRange{}, Inserted: []indexRange{{1, 2, 1}}},
+				{Removed: []indexRange{{1, 2, 1}}, Inserted: []indexRange{}},
+				{Removed: []indexRange{}, Inserted: []indexRange{{20, 20, 1}}},
+				{Removed: []indexRange{{20, 20, 1}}, Inserted: []indexRange{}},
+			}
+
+			assert.Equal(t, expected, changes)
+		})
+	}
+}
+
+func TestObserveChannel(t *testing.T) {
+	s := &Set{Implementation: NewPersistentTree()}
+	changes, stop := s.ObserveChannel(2)
+
+	assert.Nil(t, s.Add(indexRange{1, 5, 1}))
+	assert.Nil(t, s.Add(indexRange{1, 5, -1}))
+	stop()
+
+	assert.Equal(t, Change{Removed: []indexRange{}, Inserted: []indexRange{{1, 5, 1}}}, <-changes)
+	assert.Equal(t, Change{Removed: []indexRange{{1, 5, 1}}, Inserted: []indexRange{}}, <-changes)
+
+	_, open := <-changes
+	assert.False(t, open)
+
+	assert.Nil(t, s.Add(indexRange{1, 5, 1}))
+}
+
+func TestObserveStopWhileNotified(t *testing.T) {
+	s := &Set{Implementation: NewLinkedList()}
+	calls := 0
+
+	var stop func()
+	stop = s.Observe(
+		func(Change) {
+			calls++
+			stop()
+		},
+	)
+
+	other := 0
+	s.Observe(func(Change) { other++ })
+
+	assert.Nil(t, s.Add(indexRange{1, 1, 1}))
+	assert.Nil(t, s.Add(indexRange{2, 2, 1}))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 2, other)
+}
+
+// TestObserveMirror keeps a copy of a set up to date from its Changes alone,
+// as a derived index would.
+func TestObserveMirror(t *testing.T) {
+	for name, newImplementation := range implementationsToTest(t) {
+		t.Run(name, func(t *testing.T) {
+			random := rand.New(rand.NewSource(1))
+			s := &Set{Implementation: newImplementation(), History: &History{}}
+			mirror := make(map[indexRange]bool)
+
+			s.Observe(
+				func(change Change) {
+					for _, r := range change.Removed {
+						assert.True(t, mirror[r], "%s", r)
+						delete(mirror, r)
+					}
+
+					for _, r := range change.Inserted {
+						mirror[r] = true
+					}
+				},
+			)
+
+			for i := 0; i < 200; i++ {
+				switch random.Intn(6) {
+				case 0:
+					s.Undo()
+
+				case 1:
+					s.Redo()
+
+				default:
+					left := random.Int63n(50)
+					s.Add(indexRange{left, left + random.Int63n(10), random.Int63n(7) - 3})
+				}
+
+				assert.Equal(t, collectIndexRanges(t, s), sortedRanges(mirror))
+			}
+		})
+	}
+}
+
+func TestSnapshotConcurrentWithObserve(t *testing.T) {
+	s := &Set{Implementation: NewPersistentTree()}
+	changes := 0
+	s.Observe(func(Change) { changes++ })
+	count := 100
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < count; i++ {
+			left := int64(i * 10)
+			assert.Nil(t, s.Add(indexRange{left, left + 5, 1}))
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < count; i++ {
+			snapshot, err := s.Snapshot()
+			assert.Nil(t, err)
+			assert.Nil(t, snapshot.Validate())
+		}
+	}()
+
+	wg.Wait()
+
+	assert.Equal(t, count, changes)
+}
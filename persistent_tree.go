@@ -0,0 +1,388 @@
+package indexset
+
+import (
+	"sync/atomic"
+)
+
+// treeNode is an immutable AVL node. Once a node is reachable from a
+// published root it is never modified; every write copies the path from the
+// root to the changed node instead.
+type treeNode struct {
+	indexRange indexRange
+	before     *treeNode
+	after      *treeNode
+	height     int
+	maxWeight  int64
+}
+
+func makeTreeNode(r indexRange, before, after *treeNode) *treeNode {
+	n := &treeNode{
+		indexRange: r,
+		before:     before,
+		after:      after,
+		height:     1 + maxInt(before.getHeight(), after.getHeight()),
+		maxWeight:  r.weight,
+	}
+
+	if before != nil && before.maxWeight > n.maxWeight {
+		n.maxWeight = before.maxWeight
+	}
+
+	if after != nil && after.maxWeight > n.maxWeight {
+		n.maxWeight = after.maxWeight
+	}
+
+	return n
+}
+
+func (n *treeNode) getHeight() int {
+	if n == nil {
+		return 0
+	}
+
+	return n.height
+}
+
+func (n *treeNode) balance() int {
+	return n.before.getHeight() - n.after.getHeight()
+}
+
+func (n *treeNode) rotateAfter() *treeNode {
+	pivot := n.before
+	return makeTreeNode(
+		pivot.indexRange,
+		pivot.before,
+		makeTreeNode(n.indexRange, pivot.after, n.after),
+	)
+}
+
+func (n *treeNode) rotateBefore() *treeNode {
+	pivot := n.after
+	return makeTreeNode(
+		pivot.indexRange,
+		makeTreeNode(n.indexRange, n.before, pivot.before),
+		pivot.after,
+	)
+}
+
+// rebalance expects n to be a freshly copied node.
+func (n *treeNode) rebalance() *treeNode {
+	switch b := n.balance(); {
+	case b > 1:
+		if n.before.balance() < 0 {
+			n = makeTreeNode(n.indexRange, n.before.rotateBefore(), n.after)
+		}
+
+		return n.rotateAfter()
+
+	case b < -1:
+		if n.after.balance() > 0 {
+			n = makeTreeNode(n.indexRange, n.before, n.after.rotateAfter())
+		}
+
+		return n.rotateBefore()
+
+	default:
+		return n
+	}
+}
+
+func (a indexRange) lessThan(b indexRange) bool {
+	return a.left < b.left || (a.left == b.left && a.right < b.right)
+}
+
+func (n *treeNode) insert(r indexRange) *treeNode {
+	if n == nil {
+		return makeTreeNode(r, nil, nil)
+	}
+
+	if r.lessThan(n.indexRange) {
+		return makeTreeNode(n.indexRange, n.before.insert(r), n.after).rebalance()
+	}
+
+	return makeTreeNode(n.indexRange, n.before, n.after.insert(r)).rebalance()
+}
+
+func (n *treeNode) removeFirst() (rest *treeNode, first indexRange) {
+	if n.before == nil {
+		return n.after, n.indexRange
+	}
+
+	before, first := n.before.removeFirst()
+	return makeTreeNode(n.indexRange, before, n.after).rebalance(), first
+}
+
+func (n *treeNode) remove(r indexRange) (*treeNode, bool) {
+	if n == nil {
+		return nil, false
+	}
+
+	switch {
+	case r == n.indexRange:
+		if n.before == nil {
+			return n.after, true
+		}
+
+		if n.after == nil {
+			return n.before, true
+		}
+
+		after, successor := n.after.removeFirst()
+		return makeTreeNode(successor, n.before, after).rebalance(), true
+
+	case r.lessThan(n.indexRange):
+		before, ok := n.before.remove(r)
+
+		if !ok {
+			return n, false
+		}
+
+		return makeTreeNode(n.indexRange, before, n.after).rebalance(), true
+
+	default:
+		after, ok := n.after.remove(r)
+
+		if !ok {
+			return n, false
+		}
+
+		return makeTreeNode(n.indexRange, n.before, after).rebalance(), true
+	}
+}
+
+func (n *treeNode) do(f func(Member) bool) (stop bool) {
+	if n == nil {
+		return false
+	}
+
+	if n.before.do(f) {
+		return true
+	}
+
+	if f(treeMember(n.indexRange)) {
+		return true
+	}
+
+	return n.after.do(f)
+}
+
+func (n *treeNode) checkedDo(f func(Member) bool) error {
+	checker := segmentChecker{op: "do"}
+	var err error
+
+	n.do(
+		func(m Member) bool {
+			if err = checker.check(m.IndexRange()); err != nil {
+				return true
+			}
+
+			return f(m)
+		},
+	)
+
+	return err
+}
+
+// validate checks the cached height and max weight of every node and the AVL
+// balance, then the order of the segments themselves.
+func (n *treeNode) validate() error {
+	var validateNode func(n *treeNode) error
+
+	validateNode = func(n *treeNode) error {
+		if n == nil {
+			return nil
+		}
+
+		if err := validateNode(n.before); err != nil {
+			return err
+		}
+
+		if err := validateNode(n.after); err != nil {
+			return err
+		}
+
+		expected := makeTreeNode(n.indexRange, n.before, n.after)
+
+		if n.height != expected.height || n.maxWeight != expected.maxWeight || n.balance() < -1 || n.balance() > 1 {
+			return &RangeError{Op: "validate", Ranges: []indexRange{n.indexRange}, Err: ErrUnbalanced}
+		}
+
+		return nil
+	}
+
+	if err := validateNode(n); err != nil {
+		return err
+	}
+
+	checker := segmentChecker{op: "validate"}
+	var err error
+
+	n.do(
+		func(m Member) bool {
+			err = checker.check(m.IndexRange())
+			return err != nil
+		},
+	)
+
+	return err
+}
+
+func (n *treeNode) findOverlapping(overlap indexRange, overlapping []Member) []Member {
+	if n == nil {
+		return overlapping
+	}
+
+	if overlap.left <= n.indexRange.left {
+		overlapping = n.before.findOverlapping(overlap, overlapping)
+	}
+
+	if n.indexRange.comparePosition(overlap) == comparisonPositionOverlap {
+		overlapping = append(overlapping, treeMember(n.indexRange))
+	}
+
+	if overlap.right >= n.indexRange.right {
+		overlapping = n.after.findOverlapping(overlap, overlapping)
+	}
+
+	return overlapping
+}
+
+func (n *treeNode) max() int64 {
+	if n == nil {
+		return 0
+	}
+
+	return n.maxWeight
+}
+
+// treeMember identifies a segment by value, since tree nodes are copied on
+// every write and their addresses are not stable.
+type treeMember indexRange
+
+func (m treeMember) IndexRange() indexRange {
+	return indexRange(m)
+}
+
+// persistentTree is an Implementation backed by a path-copying AVL tree.
+// Writes build a new root without touching nodes reachable from older roots,
+// so Snapshot can hand out the last published root to readers in O(1)
+// without any locking.
+type persistentTree struct {
+	root      *treeNode
+	batches   int
+	published atomic.Pointer[treeNode]
+}
+
+func NewPersistentTree() Implementation {
+	return &persistentTree{}
+}
+
+func (t *persistentTree) setRoot(root *treeNode) {
+	t.root = root
+
+	if t.batches == 0 {
+		t.published.Store(root)
+	}
+}
+
+// beginBatch and endBatch nest, and the root is published when the
+// outermost batch ends.
+func (t *persistentTree) beginBatch() {
+	t.batches++
+}
+
+func (t *persistentTree) endBatch() {
+	t.batches--
+
+	if t.batches == 0 {
+		t.published.Store(t.root)
+	}
+}
+
+func (t *persistentTree) Snapshot() Implementation {
+	return &treeSnapshot{root: t.published.Load()}
+}
+
+func (t *persistentTree) FindOverlapping(overlap indexRange) []Member {
+	return t.root.findOverlapping(overlap, make([]Member, 0))
+}
+
+func (t *persistentTree) Replace(original Member, replacements ...indexRange) error {
+	root, ok := t.root.remove(original.IndexRange())
+
+	if !ok {
+		return &RangeError{Op: "replace", Ranges: []indexRange{original.IndexRange()}, Err: ErrMemberNotFound}
+	}
+
+	for _, r := range replacements {
+		root = root.insert(r)
+	}
+
+	t.setRoot(root)
+
+	return nil
+}
+
+func (t *persistentTree) AddOrFindOverlapping(newRange indexRange) (overlapping []Member, err error) {
+	overlapping = t.root.findOverlapping(newRange, nil)
+
+	if len(overlapping) == 0 {
+		t.setRoot(t.root.insert(newRange))
+	}
+
+	return overlapping, nil
+}
+
+func (t *persistentTree) Do(f func(Member) (stop bool)) error {
+	return t.root.checkedDo(f)
+}
+
+func (t *persistentTree) Validate() error {
+	return t.root.validate()
+}
+
+func (t *persistentTree) Max() int64 {
+	return t.root.max()
+}
+
+// treeSnapshot is a read-only view of a persistentTree at the time Snapshot
+// was called.
+type treeSnapshot struct {
+	root *treeNode
+}
+
+func (s *treeSnapshot) FindOverlapping(overlap indexRange) []Member {
+	return s.root.findOverlapping(overlap, make([]Member, 0))
+}
+
+func (s *treeSnapshot) Replace(_ Member, _ ...indexRange) error {
+	return ErrReadOnly
+}
+
+func (s *treeSnapshot) AddOrFindOverlapping(_ indexRange) ([]Member, error) {
+	return nil, ErrReadOnly
+}
+
+func (s *treeSnapshot) Do(f func(Member) (stop bool)) error {
+	return s.root.checkedDo(f)
+}
+
+func (s *treeSnapshot) Validate() error {
+	return s.root.validate()
+}
+
+func (s *treeSnapshot) Max() int64 {
+	return s.root.max()
+}
+
+func (s *treeSnapshot) Snapshot() Implementation {
+	return s
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,93 @@
+package indexset
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func collectIndexRanges(t *testing.T, s *Set) []indexRange {
+	ranges := make([]indexRange, 0)
+
+	err := s.Do(
+		func(m Member) bool {
+			ranges = append(ranges, m.IndexRange())
+			return false
+		},
+	)
+
+	assert.Nil(t, err)
+
+	return ranges
+}
+
+func TestSnapshotIsImmutable(t *testing.T) {
+	set := &Set{Implementation: NewPersistentTree()}
+
+	assert.Nil(t, set.Add(indexRange{1, 5, 1}))
+
+	snapshot, err := set.Snapshot()
+	assert.Nil(t, err)
+
+	assert.Nil(t, set.Add(indexRange{1, 5, 1}))
+	assert.Nil(t, set.Add(indexRange{10, 12, 4}))
+
+	assert.Equal(t, []indexRange{{1, 5, 1}}, collectIndexRanges(t, snapshot))
+	assert.Equal(t, int64(1), snapshot.Max())
+
+	assert.Equal(t, []indexRange{{1, 5, 2}, {10, 12, 4}}, collectIndexRanges(t, set))
+	assert.Equal(t, int64(4), set.Max())
+
+	assert.NotNil(t, snapshot.Add(indexRange{20, 30, 1}))
+}
+
+func TestSnapshotUnsupported(t *testing.T) {
+	set := &Set{Implementation: &linkedList{}}
+
+	_, err := set.Snapshot()
+	assert.NotNil(t, err)
+}
+
+func TestSnapshotConcurrentWithAdd(t *testing.T) {
+	set := &Set{Implementation: NewPersistentTree()}
+	count := 200
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < count; i++ {
+			left := int64(i * 10)
+			assert.Nil(t, set.Add(indexRange{left, left + 5, int64(i + 1)}))
+		}
+	}()
+
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < count; i++ {
+				snapshot, err := set.Snapshot()
+				assert.Nil(t, err)
+
+				ranges := collectIndexRanges(t, snapshot)
+
+				if len(ranges) > 0 {
+					assert.Equal(t, int64(len(ranges)), snapshot.Max())
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	snapshot, err := set.Snapshot()
+	assert.Nil(t, err)
+	assert.Len(t, collectIndexRanges(t, snapshot), count)
+	assert.Equal(t, int64(count), snapshot.Max())
+}
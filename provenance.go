@@ -0,0 +1,131 @@
+package indexset
+
+import (
+	"sort"
+)
+
+// contribution is one Add, with the ID it was made under if it has one.
+type contribution struct {
+	ids []string
+	r   indexRange
+}
+
+// Provenance records, for every segment of a Set, the IDs of the Adds made
+// through AddFrom and SubtractFrom that overlapped it. A segment split off
+// another keeps its IDs, a segment whose weight returns to 0 loses them, and
+// Undo, Redo and Rollback give the segments they restore back the IDs they
+// had. Segments made before Provenance was set, or only by Adds without an
+// ID, have none.
+type Provenance struct {
+	//sorted and never changed once set, so that segments can share them
+	segments map[indexRange][]string
+}
+
+// AddFrom adds newRange as Add does, recording id as a contributor to every
+// segment it overlaps if s has a Provenance.
+func (s *Set) AddFrom(id string, newRange indexRange) error {
+	return s.addFrom(contribution{ids: []string{id}, r: newRange})
+}
+
+// SubtractFrom subtracts oldRange as Subtract does, recording id as a
+// contributor to every segment it overlaps if s has a Provenance.
+func (s *Set) SubtractFrom(id string, oldRange indexRange) error {
+	negated, err := negate(oldRange)
+
+	if err != nil {
+		return err
+	}
+
+	return s.AddFrom(id, negated)
+}
+
+// ContributorsAt returns the sorted IDs of the Adds that contributed to the
+// segment covering index. It returns nil if s has no Provenance or no
+// segment covers index.
+func (s *Set) ContributorsAt(index int64) []string {
+	for _, m := range s.FindOverlapping(indexRange{index, index, 0}) {
+		return append([]string(nil), s.Provenance.idsOf(m.IndexRange())...)
+	}
+
+	return nil
+}
+
+// The methods below do nothing on a nil Provenance, so that Set can call
+// them whether or not it tracks contributors.
+
+func (p *Provenance) idsOf(r indexRange) []string {
+	if p == nil {
+		return nil
+	}
+
+	return p.segments[r]
+}
+
+// set records ids as the contributors of the segment r, or forgets r if ids
+// is empty.
+func (p *Provenance) set(r indexRange, ids []string) {
+	if p == nil {
+		return
+	}
+
+	if len(ids) == 0 {
+		delete(p.segments, r)
+		return
+	}
+
+	if p.segments == nil {
+		p.segments = make(map[indexRange][]string)
+	}
+
+	p.segments[r] = ids
+}
+
+// split moves the contributors of original to the pieces Add replaced it
+// with, adding those of c to the pieces c overlaps. Pieces that lie outside
+// original came from c alone.
+func (p *Provenance) split(original indexRange, pieces []indexRange, c contribution) {
+	if p == nil {
+		return
+	}
+
+	ids := p.segments[original]
+	delete(p.segments, original)
+
+	for _, piece := range pieces {
+		var pieceIDs []string
+
+		if piece.comparePosition(original) == comparisonPositionOverlap {
+			pieceIDs = ids
+		}
+
+		if piece.comparePosition(c.r) == comparisonPositionOverlap {
+			pieceIDs = mergeIDs(pieceIDs, c.ids)
+		}
+
+		p.set(piece, pieceIDs)
+	}
+}
+
+// mergeIDs returns the sorted union of a and b, which are sorted. It returns
+// a itself if b adds nothing to it.
+func mergeIDs(a, b []string) []string {
+	merged := append([]string(nil), a...)
+
+	for _, id := range b {
+		i := sort.SearchStrings(merged, id)
+
+		if i < len(merged) && merged[i] == id {
+			continue
+		}
+
+		merged = append(merged, "")
+		copy(merged[i+1:], merged[i:])
+		merged[i] = id
+	}
+
+	if len(merged) == len(a) {
+		return a
+	}
+
+	return merged
+}
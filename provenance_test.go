@@ -0,0 +1,161 @@
+package indexset
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContributorsAt(t *testing.T) {
+	for name, newImplementation := range implementationsToTest(t) {
+		t.Run(name, func(t *testing.T) {
+			s := &Set{Implementation: newImplementation(), Provenance: &Provenance{}}
+
+			assert.Nil(t, s.AddFrom("a", indexRange{0, 9, 1}))
+			assert.Nil(t, s.AddFrom("b", indexRange{5, 14, 1}))
+			assert.Nil(t, s.Add(indexRange{0, 2, 1}))
+			assert.Nil(t, s.SubtractFrom("c", indexRange{12, 14, 1}))
+			assert.Nil(t, s.AddFrom("a", indexRange{7, 7, 1}))
+
+			for index, expected := range map[int64][]string{
+				0:  {"a"},
+				3:  {"a"},
+				6:  {"a", "b"},
+				7:  {"a", "b"},
+				10: {"b"},
+				13: nil,
+				20: nil,
+			} {
+				assert.Equal(t, expected, s.ContributorsAt(index), "index %d", index)
+			}
+
+			assert.Nil(t, (&Set{Implementation: newImplementation()}).ContributorsAt(0))
+		})
+	}
+}
+
+func TestContributorsAtRestored(t *testing.T) {
+	s := &Set{Implementation: NewPersistentTree(), Provenance: &Provenance{}, History: &History{}}
+
+	assert.Nil(t, s.AddFrom("a", indexRange{3, 5, 1}))
+	assert.Nil(t, s.AddFrom("b", indexRange{3, 5, 1}))
+	assert.Equal(t, []string{"a", "b"}, s.ContributorsAt(4))
+
+	assert.Nil(t, s.Undo())
+	assert.Equal(t, []string{"a"}, s.ContributorsAt(4))
+
+	assert.Nil(t, s.Redo())
+	assert.Equal(t, []string{"a", "b"}, s.ContributorsAt(4))
+
+	tx := s.Begin()
+	assert.Nil(t, s.SubtractFrom("c", indexRange{4, 4, 2}))
+	assert.Nil(t, s.AddFrom("d", indexRange{4, 4, 2}))
+	assert.Equal(t, []string{"d"}, s.ContributorsAt(4))
+	assert.Nil(t, tx.Rollback())
+	assert.Equal(t, []string{"a", "b"}, s.ContributorsAt(4))
+}
+
+// provenanceModel tracks the weight and contributors of every index on its
+// own, forgetting the contributors of an index whose weight returns to 0.
+type provenanceModel struct {
+	weights      map[int64]int64
+	contributors map[int64]map[string]bool
+}
+
+func (m provenanceModel) addFrom(id string, r indexRange) provenanceModel {
+	next := provenanceModel{
+		weights:      make(map[int64]int64),
+		contributors: make(map[int64]map[string]bool),
+	}
+
+	for index, weight := range m.weights {
+		next.weights[index] = weight
+		next.contributors[index] = m.contributors[index]
+	}
+
+	for index := r.left; index <= r.right; index++ {
+		next.weights[index] += r.weight
+
+		if next.weights[index] == 0 {
+			delete(next.weights, index)
+			delete(next.contributors, index)
+			continue
+		}
+
+		contributors := map[string]bool{id: true}
+
+		for other := range next.contributors[index] {
+			contributors[other] = true
+		}
+
+		next.contributors[index] = contributors
+	}
+
+	return next
+}
+
+func (m provenanceModel) contributorsAt(index int64) []string {
+	if len(m.contributors[index]) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0)
+
+	for id := range m.contributors[index] {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	return ids
+}
+
+func TestContributorsAtRandom(t *testing.T) {
+	for name, newImplementation := range implementationsToTest(t) {
+		t.Run(name, func(t *testing.T) {
+			random := rand.New(rand.NewSource(1))
+			s := &Set{Implementation: newImplementation(), Provenance: &Provenance{}, History: &History{}}
+			states := []provenanceModel{{}}
+			current := 0
+
+			for i := 0; i < 300; i++ {
+				switch random.Intn(8) {
+				case 0:
+					if s.Undo() == nil {
+						current--
+					}
+
+				case 1:
+					if s.Redo() == nil {
+						current++
+					}
+
+				case 2:
+					tx := s.Begin()
+					s.AddFrom("rolled back", indexRange{0, 40, 1})
+					assert.Nil(t, tx.Rollback())
+
+				default:
+					id := fmt.Sprint(random.Intn(5))
+					left := random.Int63n(40)
+					r := indexRange{left, left + random.Int63n(8), random.Int63n(5) - 2}
+
+					if r.weight == 0 {
+						continue
+					}
+
+					assert.Nil(t, s.AddFrom(id, r))
+					states = append(states[:current+1], states[current].addFrom(id, r))
+					current++
+				}
+
+				for index := int64(0); index < 50; index++ {
+					assert.Equal(t, states[current].contributorsAt(index), s.ContributorsAt(index), "step %d index %d", i, index)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package indexset
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+const (
+	renderDefaultWidth  = 80
+	renderDefaultHeight = 10
+)
+
+var renderBlocks = []rune(" ▁▂▃▄▅▆▇█")
+
+// RenderOptions controls how Render draws a Set.
+type RenderOptions struct {
+	// Width is the number of columns used for the coordinates, 80 by default.
+	// When the window is wider than that, each column shows the largest
+	// weight of the coordinates it covers.
+	Width int
+	// Height is the number of rows used for the largest weight, 10 by default.
+	Height int
+	// Window limits the coordinates drawn. By default it spans from the first
+	// to the last segment.
+	Window *indexRange
+	// Unicode draws bars with block elements at eighth-row resolution
+	// instead of '#'.
+	Unicode bool
+}
+
+// Render draws s as a histogram of weight over coordinate, with the largest
+// weight labelled on the y axis and the window bounds on the x axis. Weights
+// below 0 are drawn as empty columns. Nothing is written for an empty set
+// without a window.
+func (s *Set) Render(w io.Writer, options RenderOptions) error {
+	window, err := s.renderWindow(options.Window)
+
+	if err != nil || window == nil {
+		return err
+	}
+
+	width, height := options.Width, options.Height
+
+	if width <= 0 {
+		width = renderDefaultWidth
+	}
+
+	if height <= 0 {
+		height = renderDefaultHeight
+	}
+
+	//span wraps to 0 when the window covers every int64, and then stands
+	//for 2^64
+	span := uint64(window.right-window.left) + 1
+	columns := width
+
+	if span != 0 && span < uint64(columns) {
+		columns = int(span)
+	}
+
+	columnOf := func(x int64) int {
+		hi, lo := bits.Mul64(uint64(x-window.left), uint64(columns))
+
+		if span == 0 {
+			return int(hi)
+		}
+
+		column, _ := bits.Div64(hi, lo, span)
+		return int(column)
+	}
+
+	weights := make([]int64, columns)
+	max := int64(0)
+
+	for _, m := range s.FindOverlapping(*window) {
+		r := m.IndexRange()
+
+		if r.left < window.left {
+			r.left = window.left
+		}
+
+		if r.right > window.right {
+			r.right = window.right
+		}
+
+		for column := columnOf(r.left); column <= columnOf(r.right); column++ {
+			if r.weight > weights[column] {
+				weights[column] = r.weight
+			}
+		}
+
+		if r.weight > max {
+			max = r.weight
+		}
+	}
+
+	maxLabel := strconv.FormatInt(max, 10)
+	margin := len(maxLabel)
+	sb := strings.Builder{}
+
+	for row := height - 1; row >= 0; row-- {
+		label := ""
+
+		if row == height-1 {
+			label = maxLabel
+		}
+
+		fmt.Fprintf(&sb, "%*s ", margin, label)
+
+		if options.Unicode {
+			sb.WriteRune('┤')
+		} else {
+			sb.WriteRune('|')
+		}
+
+		line := make([]rune, columns)
+
+		for column, weight := range weights {
+			line[column] = renderCell(weight, max, height, row, options.Unicode)
+		}
+
+		sb.WriteString(strings.TrimRight(string(line), " "))
+		sb.WriteString("\n")
+	}
+
+	if options.Unicode {
+		fmt.Fprintf(&sb, "%*s └%s\n", margin, "0", strings.Repeat("─", columns))
+	} else {
+		fmt.Fprintf(&sb, "%*s +%s\n", margin, "0", strings.Repeat("-", columns))
+	}
+
+	leftLabel := strconv.FormatInt(window.left, 10)
+	rightLabel := strconv.FormatInt(window.right, 10)
+	fmt.Fprintf(&sb, "%*s  %s", margin, "", leftLabel)
+
+	if padding := columns - len(leftLabel) - len(rightLabel); padding > 0 {
+		fmt.Fprintf(&sb, "%s%s", strings.Repeat(" ", padding), rightLabel)
+	}
+
+	sb.WriteString("\n")
+
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+func (s *Set) renderWindow(window *indexRange) (*indexRange, error) {
+	if window != nil {
+		return window, nil
+	}
+
+	var first, last Member
+
+	err := s.Do(
+		func(m Member) bool {
+			if first == nil {
+				first = m
+			}
+
+			last = m
+			return false
+		},
+	)
+
+	if err != nil || first == nil {
+		return nil, err
+	}
+
+	return &indexRange{first.IndexRange().left, last.IndexRange().right, 0}, nil
+}
+
+// renderCell returns the character drawn for weight in the given row, where
+// row 0 sits on the x axis. Any positive weight fills at least part of row 0.
+func renderCell(weight, max int64, height, row int, unicode bool) rune {
+	if weight <= 0 || max <= 0 {
+		return ' '
+	}
+
+	resolution := 1
+
+	if unicode {
+		resolution = len(renderBlocks) - 1
+	}
+
+	filled := int(float64(weight) / float64(max) * float64(height*resolution))
+
+	if filled == 0 {
+		filled = 1
+	}
+
+	level := filled - row*resolution
+
+	switch {
+	case level <= 0:
+		return ' '
+
+	case !unicode:
+		return '#'
+
+	case level >= resolution:
+		return renderBlocks[resolution]
+
+	default:
+		return renderBlocks[level]
+	}
+}
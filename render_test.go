@@ -0,0 +1,108 @@
+package indexset
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func renderTestSet(t *testing.T, newImplementation func() Implementation) *Set {
+	s := &Set{Implementation: newImplementation()}
+
+	for _, r := range []indexRange{{0, 3, 2}, {2, 5, 2}, {8, 9, 1}} {
+		assert.Nil(t, s.Add(r))
+	}
+
+	return s
+}
+
+func TestRender(t *testing.T) {
+	testCases := []struct {
+		description string
+		options     RenderOptions
+		expected    string
+	}{
+		{
+			description: "ascii",
+			options:     RenderOptions{Height: 4},
+			expected: "4 |  ##\n" +
+				"  |  ##\n" +
+				"  |######\n" +
+				"  |######  ##\n" +
+				"0 +----------\n" +
+				"   0        9\n",
+		},
+		{
+			description: "unicode",
+			options:     RenderOptions{Height: 2, Unicode: true},
+			expected: "4 ┤  ██\n" +
+				"  ┤██████  ▄▄\n" +
+				"0 └──────────\n" +
+				"   0        9\n",
+		},
+		{
+			description: "scaled",
+			options:     RenderOptions{Width: 5, Height: 2},
+			expected: "4 | #\n" +
+				"  |### #\n" +
+				"0 +-----\n" +
+				"   0   9\n",
+		},
+		{
+			description: "window",
+			options:     RenderOptions{Height: 2, Window: &indexRange{4, 8, 0}},
+			expected: "2 |##\n" +
+				"  |##  #\n" +
+				"0 +-----\n" +
+				"   4   8\n",
+		},
+	}
+
+	for name, newImplementation := range implementationsToTest(t) {
+		for _, tc := range testCases {
+			t.Run(name+"/"+tc.description, func(t *testing.T) {
+				out := &bytes.Buffer{}
+				assert.Nil(t, renderTestSet(t, newImplementation).Render(out, tc.options))
+				assert.Equal(t, tc.expected, out.String())
+			})
+		}
+	}
+}
+
+func TestRenderEmpty(t *testing.T) {
+	for name, newImplementation := range implementationsToTest(t) {
+		t.Run(name, func(t *testing.T) {
+			s := &Set{Implementation: newImplementation()}
+			out := &bytes.Buffer{}
+
+			assert.Nil(t, s.Render(out, RenderOptions{}))
+			assert.Equal(t, "", out.String())
+
+			assert.Nil(t, s.Render(out, RenderOptions{Height: 1, Window: &indexRange{0, 3, 0}}))
+			assert.Equal(t, "0 |\n0 +----\n   0  3\n", out.String())
+		})
+	}
+}
+
+func TestRenderWholeRange(t *testing.T) {
+	for name, newImplementation := range implementationsToTest(t) {
+		t.Run(name, func(t *testing.T) {
+			s := &Set{Implementation: newImplementation()}
+			assert.Nil(t, s.Add(indexRange{math.MinInt64, math.MaxInt64, 1}))
+			assert.Nil(t, s.Add(indexRange{0, math.MaxInt64, 1}))
+
+			out := &bytes.Buffer{}
+			assert.Nil(t, s.Render(out, RenderOptions{Width: 4, Height: 2}))
+			assert.Equal(
+				t,
+				"2 |  ##\n"+
+					"  |####\n"+
+					"0 +----\n"+
+					"   -9223372036854775808\n",
+				out.String(),
+			)
+		})
+	}
+}
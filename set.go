@@ -2,6 +2,7 @@ package indexset
 
 import (
 	"fmt"
+	"math"
 	"strings"
 )
 
@@ -13,13 +14,74 @@ type Implementation interface {
 	FindOverlapping(overlap indexRange) []Member
 	Replace(original Member, replacements ...indexRange) error
 	AddOrFindOverlapping(indexRange) ([]Member, error)
-	Do(func(Member) (stop bool))
+	Do(func(Member) (stop bool)) error
+	Validate() error
+}
+
+// Implementations returns the constructor of every built-in Implementation,
+// keyed by name.
+func Implementations() map[string]func() Implementation {
+	return map[string]func() Implementation{
+		"linked_list":     NewLinkedList,
+		"persistent_tree": NewPersistentTree,
+	}
 }
 
 type Set struct {
 	Implementation
+
+	// Arithmetic controls how Add handles weight sums that overflow. The zero
+	// value makes Add fail with ErrWeightOverflow.
+	Arithmetic WeightArithmetic
+
+	// History, if set, records committed changes for Undo and Redo.
+	History *History
+
+	// Provenance, if set, tracks which Adds made through AddFrom contribute
+	// to every segment.
+	Provenance *Provenance
+
+	observers []*observer
+
+	//journals are the open transactions, outermost first, followed by the
+	//recorder of a mutation being observed. Writes go through the last of
+	//them and Implementation itself never changes, so Snapshot can run
+	//while another goroutine calls Add.
+	journals []Implementation
+}
+
+// batcher is implemented by Implementations that can defer making writes
+// visible until a whole Set operation has finished.
+type batcher interface {
+	beginBatch()
+	endBatch()
+}
+
+type snapshotter interface {
+	Snapshot() Implementation
+}
+
+type maxer interface {
+	Max() int64
 }
 
+// Snapshot returns an immutable view of the set that can be read while
+// other goroutines keep calling Add on s, even if s has a History or
+// observers. The Implementation must support snapshots.
+func (s *Set) Snapshot() (*Set, error) {
+	snapshotter, ok := s.Implementation.(snapshotter)
+
+	if !ok {
+		return nil, fmt.Errorf("%w: snapshot: %T", ErrNotSupported, s.Implementation)
+	}
+
+	return &Set{Implementation: snapshotter.Snapshot()}, nil
+}
+
+// Nth returns the n-th segment in order, or nil if there are fewer. Like
+// Max, String and FindOverlapping, it has no error to return: if it reaches
+// a corrupted segment it stops there and answers from the segments before
+// it. Validate, or the error returned by Do, tells whether that happened.
 func (s *Set) Nth(n int) Member {
 	i := 0
 	var found Member
@@ -39,37 +101,209 @@ func (s *Set) Nth(n int) Member {
 	return found
 }
 
+// Add sums the weight of newRange into every index it covers. An index whose
+// weight sums to 0 is no longer covered by any segment, so adding a range
+// with a weight of 0 changes nothing. If Add fails, the set is left as it
+// was before the call.
 func (s *Set) Add(newRange indexRange) error {
-	overlapping, err := s.AddOrFindOverlapping(newRange)
+	return s.addFrom(contribution{r: newRange})
+}
+
+func (s *Set) addFrom(c contribution) error {
+	if c.r.weight == 0 {
+		return nil
+	}
+
+	if s.History != nil && !s.inTransaction() {
+		tx := s.Begin()
+
+		if err := tx.apply(func() error { return s.addFrom(c) }); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		return tx.Commit()
+	}
+
+	return s.observe(func() error { return s.add(c) })
+}
+
+func (s *Set) add(c contribution) error {
+	writer := s.writer()
+
+	if batcher, ok := writer.(batcher); ok {
+		batcher.beginBatch()
+		defer batcher.endBatch()
+	}
+
+	overlapping, err := writer.AddOrFindOverlapping(c.r)
 
 	if err != nil {
 		return err
 	}
 
 	if len(overlapping) == 0 {
+		s.Provenance.set(c.r, c.ids)
 		return nil
 	}
 
-	carryover := newRange
+	carryover := c.r
+	replacements := make([][]indexRange, len(overlapping))
 
-	for _, currentNode := range overlapping {
-		currentRange := currentNode.IndexRange()
-
-		var replacements []indexRange
-		replacements, carryover, err = currentRange.SplitWith(carryover)
+	//split every overlapping segment before replacing any of them, so that a
+	//failed split leaves the set as it was
+	for i, currentNode := range overlapping {
+		replacements[i], carryover, err = currentNode.IndexRange().splitWith(carryover, s.Arithmetic)
 
 		if err != nil {
 			return err
 		}
+	}
+
+	j := &journal{Implementation: writer, provenance: s.Provenance}
+	err = s.replaceAll(j, c, overlapping, replacements, carryover)
+
+	if err == nil {
+		return nil
+	}
+
+	if undoErr := undoJournal(writer, j.entries, s.Provenance); undoErr != nil {
+		return fmt.Errorf("%w; undoing it failed: %s", err, undoErr)
+	}
+
+	return err
+}
+
+// replaceAll applies the splits computed by add through j, so that they can
+// be undone if the Implementation fails partway.
+func (s *Set) replaceAll(
+	j *journal,
+	c contribution,
+	overlapping []Member,
+	replacements [][]indexRange,
+	carryover indexRange,
+) error {
+	for i, currentNode := range overlapping {
+		original := currentNode.IndexRange()
+		pieces := withoutZeroWeights(replacements[i])
 
-		if err = s.Replace(currentNode, replacements...); err != nil {
+		if err := j.Replace(currentNode, pieces...); err != nil {
 			return err
 		}
+
+		s.Provenance.split(original, pieces, c)
+	}
+
+	if carryover == indexRangeZero {
+		return nil
 	}
 
+	//whatever is left of newRange lies past every overlapping segment
+	overlapping, err := j.AddOrFindOverlapping(carryover)
+
+	if err != nil {
+		return err
+	}
+
+	if len(overlapping) > 0 {
+		return &RangeError{Op: "add", Ranges: []indexRange{c.r, carryover}, Err: ErrImpossibleState}
+	}
+
+	s.Provenance.set(carryover, c.ids)
+
 	return nil
 }
 
+func withoutZeroWeights(ranges []indexRange) []indexRange {
+	nonZero := ranges[:0]
+
+	for _, r := range ranges {
+		if r.weight != 0 {
+			nonZero = append(nonZero, r)
+		}
+	}
+
+	return nonZero
+}
+
+// Subtract removes the weight of oldRange from every index it covers.
+func (s *Set) Subtract(oldRange indexRange) error {
+	negated, err := negate(oldRange)
+
+	if err != nil {
+		return err
+	}
+
+	return s.Add(negated)
+}
+
+func negate(oldRange indexRange) (indexRange, error) {
+	if oldRange.weight == math.MinInt64 {
+		return indexRange{}, &RangeError{Op: "subtract", Ranges: []indexRange{oldRange}, Err: ErrWeightOverflow}
+	}
+
+	negated := oldRange
+	negated.weight = -oldRange.weight
+
+	return negated, nil
+}
+
+// At returns the weight at index, which is 0 for indices no segment covers.
+func (s *Set) At(index int64) int64 {
+	for _, m := range s.FindOverlapping(indexRange{index, index, 0}) {
+		return m.IndexRange().weight
+	}
+
+	return 0
+}
+
+// Gaps returns the ranges inside window that no segment covers, with a
+// weight of 0.
+func (s *Set) Gaps(window indexRange) ([]indexRange, error) {
+	gaps := make([]indexRange, 0)
+	next := window.left
+	covered := false
+
+	err := s.Do(
+		func(m Member) bool {
+			r := m.IndexRange()
+
+			if r.right < next {
+				return false
+			}
+
+			if r.left > window.right {
+				return true
+			}
+
+			if r.left > next {
+				gaps = append(gaps, indexRange{next, r.left - 1, 0})
+			}
+
+			//stopping here also keeps r.right+1 from wrapping at MaxInt64
+			if r.right >= window.right {
+				covered = true
+				return true
+			}
+
+			next = r.right + 1
+			return false
+		},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !covered && next <= window.right {
+		gaps = append(gaps, indexRange{next, window.right, 0})
+	}
+
+	return gaps, nil
+}
+
+// String lists the segments of i, ending with the error if a corrupted
+// segment stopped the listing.
 func (i *Set) String() string {
 	if stringer, ok := i.Implementation.(fmt.Stringer); ok {
 		return stringer.String()
@@ -78,19 +312,33 @@ func (i *Set) String() string {
 	sb := strings.Builder{}
 	sb.WriteString("\n")
 
-	i.Do(
+	err := i.Do(
 		func(m Member) bool {
 			sb.WriteString(m.IndexRange().String())
 			return false
 		},
 	)
 
+	if err != nil {
+		sb.WriteString("\n" + err.Error())
+	}
+
 	return sb.String()
 }
 
+// Max returns the largest weight of any segment, or 0 if none is larger. It
+// only considers the segments before the first corrupted one, as Nth does.
 func (s *Set) Max() int64 {
 	max := int64(0)
 
+	if maxer, ok := s.Implementation.(maxer); ok {
+		if weight := maxer.Max(); weight > max {
+			max = weight
+		}
+
+		return max
+	}
+
 	s.Do(
 		func(m Member) bool {
 			weight := m.IndexRange().weight
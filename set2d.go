@@ -0,0 +1,380 @@
+package indexset
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+)
+
+// rectangle covers the columns [left, right] of the rows [bottom, top].
+type rectangle struct {
+	left   int64
+	right  int64
+	bottom int64
+	top    int64
+	weight int64
+}
+
+// MakeRectangle checks that left is not larger than right and bottom is not
+// larger than top. Like MakeRange it accepts any int64, so a Set2D can use
+// the whole plane.
+func MakeRectangle(left, right, bottom, top, weight int64) (*rectangle, error) {
+	if _, err := MakeRange(left, right, weight); err != nil {
+		return nil, err
+	}
+
+	if _, err := MakeRange(bottom, top, weight); err != nil {
+		return nil, err
+	}
+
+	return &rectangle{left: left, right: right, bottom: bottom, top: top, weight: weight}, nil
+}
+
+func (r rectangle) Left() int64 {
+	return r.left
+}
+
+func (r rectangle) Right() int64 {
+	return r.right
+}
+
+func (r rectangle) Bottom() int64 {
+	return r.bottom
+}
+
+func (r rectangle) Top() int64 {
+	return r.top
+}
+
+func (r rectangle) Weight() int64 {
+	return r.weight
+}
+
+func (r rectangle) String() string {
+	return fmt.Sprintf("%v:|%v_%v|x|%v_%v|", r.weight, r.left, r.right, r.bottom, r.top)
+}
+
+// strip is a run of rows that all hold the same segments.
+type strip struct {
+	bottom int64
+	top    int64
+	set    *Set
+}
+
+// Set2D sums weighted rectangles over a grid. Rows are grouped into strips,
+// each holding the columns of its rows as a 1D Set, and a rectangle is added
+// by cutting the strips at its bottom and top and adding its columns to the
+// strips in between. Like the segments of a Set, strips are split but never
+// merged, so Rectangles returns the pieces the Adds cut the grid into.
+type Set2D struct {
+	// Arithmetic controls how Add handles weight sums that overflow, as it
+	// does for Set.
+	Arithmetic WeightArithmetic
+
+	newImplementation func() Implementation
+	//sorted by bottom, disjoint and never empty
+	strips []*strip
+}
+
+// NewSet2D returns an empty Set2D whose strips use newImplementation.
+func NewSet2D(newImplementation func() Implementation) *Set2D {
+	return &Set2D{newImplementation: newImplementation}
+}
+
+// Add sums the weight of r into every cell it covers. If Add fails, the set
+// is left as it was before the call.
+func (s *Set2D) Add(r rectangle) error {
+	if _, err := MakeRectangle(r.left, r.right, r.bottom, r.top, r.weight); err != nil {
+		return err
+	}
+
+	if r.weight == 0 {
+		return nil
+	}
+
+	strips, first, last, err := s.cut(r.bottom, r.top)
+
+	if err != nil {
+		return err
+	}
+
+	transactions := make([]*Transaction, 0, last-first)
+
+	for _, st := range strips[first:last] {
+		st.set.Arithmetic = s.Arithmetic
+		tx := st.set.Begin()
+		transactions = append(transactions, tx)
+
+		if err := tx.Add(indexRange{r.left, r.right, r.weight}); err != nil {
+			for i := len(transactions) - 1; i >= 0; i-- {
+				transactions[i].Rollback()
+			}
+
+			return err
+		}
+	}
+
+	for _, tx := range transactions {
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	s.strips = strips[:0:0]
+
+	for _, st := range strips {
+		if !isEmpty(st.set) {
+			s.strips = append(s.strips, st)
+		}
+	}
+
+	return nil
+}
+
+func (s *Set2D) Subtract(r rectangle) error {
+	negated, err := negate(indexRange{r.left, r.right, r.weight})
+
+	if err != nil {
+		return err
+	}
+
+	r.weight = negated.weight
+
+	return s.Add(r)
+}
+
+// cut returns a copy of the strips of s in which no strip crosses bottom or
+// top and the rows in between are covered by strips[first:last], new ones
+// being empty. Strips that are split get copies of the split Set, so the
+// strips of s are left as they were.
+func (s *Set2D) cut(bottom, top int64) (strips []*strip, first, last int, err error) {
+	strips = append([]*strip(nil), s.strips...)
+
+	if strips, err = s.splitAt(strips, bottom); err != nil {
+		return nil, 0, 0, err
+	}
+
+	if top < math.MaxInt64 {
+		if strips, err = s.splitAt(strips, top+1); err != nil {
+			return nil, 0, 0, err
+		}
+	}
+
+	first = sort.Search(len(strips), func(i int) bool { return strips[i].top >= bottom })
+	cut := append([]*strip(nil), strips[:first]...)
+	next, covered := bottom, false
+	i := first
+
+	for ; i < len(strips) && strips[i].bottom <= top; i++ {
+		if strips[i].bottom > next {
+			cut = append(cut, s.emptyStrip(next, strips[i].bottom-1))
+		}
+
+		cut = append(cut, strips[i])
+
+		if strips[i].top >= top {
+			covered = true
+		} else {
+			next = strips[i].top + 1
+		}
+	}
+
+	if !covered {
+		cut = append(cut, s.emptyStrip(next, top))
+	}
+
+	last = len(cut)
+
+	return append(cut, strips[i:]...), first, last, nil
+}
+
+// splitAt splits the strip crossing y, if any, into the rows below y and
+// the rows from y up.
+func (s *Set2D) splitAt(strips []*strip, y int64) ([]*strip, error) {
+	i := sort.Search(len(strips), func(i int) bool { return strips[i].top >= y })
+
+	if i == len(strips) || strips[i].bottom >= y {
+		return strips, nil
+	}
+
+	st := strips[i]
+	upper, err := s.clone(st.set)
+
+	if err != nil {
+		return nil, err
+	}
+
+	strips = append(strips, nil)
+	copy(strips[i+1:], strips[i:])
+	strips[i] = &strip{bottom: st.bottom, top: y - 1, set: st.set}
+	strips[i+1] = &strip{bottom: y, top: st.top, set: upper}
+
+	return strips, nil
+}
+
+func (s *Set2D) emptyStrip(bottom, top int64) *strip {
+	return &strip{bottom: bottom, top: top, set: &Set{Implementation: s.newImplementation()}}
+}
+
+func (s *Set2D) clone(set *Set) (*Set, error) {
+	clone := &Set{Implementation: s.newImplementation()}
+	var err error
+
+	doErr := set.Do(
+		func(m Member) bool {
+			err = clone.Add(m.IndexRange())
+			return err != nil
+		},
+	)
+
+	if doErr != nil {
+		return nil, doErr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return clone, nil
+}
+
+func isEmpty(set *Set) bool {
+	empty := true
+
+	set.Do(
+		func(Member) bool {
+			empty = false
+			return true
+		},
+	)
+
+	return empty
+}
+
+// stripAt returns the strip holding row y, or nil if no strip does.
+func (s *Set2D) stripAt(y int64) *strip {
+	i := sort.Search(len(s.strips), func(i int) bool { return s.strips[i].top >= y })
+
+	if i == len(s.strips) || s.strips[i].bottom > y {
+		return nil
+	}
+
+	return s.strips[i]
+}
+
+// At returns the weight of the cell at column x of row y.
+func (s *Set2D) At(x, y int64) int64 {
+	if st := s.stripAt(y); st != nil {
+		return st.set.At(x)
+	}
+
+	return 0
+}
+
+// Max returns the largest weight of any cell, or 0 if none is larger.
+func (s *Set2D) Max() int64 {
+	max := int64(0)
+
+	for _, st := range s.strips {
+		if weight := st.set.Max(); weight > max {
+			max = weight
+		}
+	}
+
+	return max
+}
+
+// Sum returns the sum of the weights of the cells in region, ignoring its
+// weight. It fails with ErrWeightOverflow if the sum does not fit an int64.
+func (s *Set2D) Sum(region rectangle) (int64, error) {
+	sum := new(big.Int)
+	area := new(big.Int)
+
+	for _, st := range s.strips {
+		if st.top < region.bottom || st.bottom > region.top {
+			continue
+		}
+
+		rows := length(clamp(st.bottom, region.bottom, region.top), clamp(st.top, region.bottom, region.top))
+
+		for _, m := range st.set.FindOverlapping(indexRange{region.left, region.right, 0}) {
+			r := m.IndexRange()
+
+			area.Set(length(clamp(r.left, region.left, region.right), clamp(r.right, region.left, region.right)))
+			area.Mul(area, rows)
+			area.Mul(area, big.NewInt(r.weight))
+			sum.Add(sum, area)
+		}
+	}
+
+	if !sum.IsInt64() {
+		return 0, &RangeError{Op: "sum", Err: ErrWeightOverflow}
+	}
+
+	return sum.Int64(), nil
+}
+
+// length returns the number of integers in [low, high], which can be more
+// than an int64 holds.
+func length(low, high int64) *big.Int {
+	n := big.NewInt(high)
+	n.Sub(n, big.NewInt(low))
+
+	return n.Add(n, big.NewInt(1))
+}
+
+// clamp returns value limited to [low, high].
+func clamp(value, low, high int64) int64 {
+	if value > high {
+		return high
+	}
+
+	if value < low {
+		return low
+	}
+
+	return value
+}
+
+// Rectangles returns the disjoint rectangles the set is made of, ordered by
+// bottom and then left.
+func (s *Set2D) Rectangles() ([]rectangle, error) {
+	rectangles := make([]rectangle, 0)
+
+	for _, st := range s.strips {
+		err := st.set.Do(
+			func(m Member) bool {
+				r := m.IndexRange()
+				rectangles = append(rectangles, rectangle{r.left, r.right, st.bottom, st.top, r.weight})
+				return false
+			},
+		)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rectangles, nil
+}
+
+// Validate checks that the strips are ordered, disjoint and not empty, and
+// validates the Set of each.
+func (s *Set2D) Validate() error {
+	for i, st := range s.strips {
+		if st.bottom > st.top || isEmpty(st.set) {
+			return fmt.Errorf("%w: strip %d_%d", ErrEmptySegment, st.bottom, st.top)
+		}
+
+		if i > 0 && s.strips[i-1].top >= st.bottom {
+			return fmt.Errorf("%w: strips %d_%d and %d_%d", ErrOverlap, s.strips[i-1].bottom, s.strips[i-1].top, st.bottom, st.top)
+		}
+
+		if err := st.set.Validate(); err != nil {
+			return fmt.Errorf("strip %d_%d: %w", st.bottom, st.top, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package indexset
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func makeTestRectangle(t *testing.T, left, right, bottom, top, weight int64) rectangle {
+	r, err := MakeRectangle(left, right, bottom, top, weight)
+	assert.Nil(t, err)
+	return *r
+}
+
+func TestSet2D(t *testing.T) {
+	for name, newImplementation := range implementationsToTest(t) {
+		t.Run(name, func(t *testing.T) {
+			s := NewSet2D(newImplementation)
+			assert.Nil(t, s.Add(makeTestRectangle(t, 0, 3, 0, 3, 1)))
+			assert.Nil(t, s.Add(makeTestRectangle(t, 2, 5, 2, 5, 2)))
+			assert.Nil(t, s.Validate())
+
+			rectangles, err := s.Rectangles()
+			assert.Nil(t, err)
+			assert.Equal(
+				t,
+				[]rectangle{
+					{0, 3, 0, 1, 1},
+					{0, 1, 2, 3, 1},
+					{2, 3, 2, 3, 3},
+					{4, 5, 2, 3, 2},
+					{2, 5, 4, 5, 2},
+				},
+				rectangles,
+			)
+
+			assert.Equal(t, int64(3), s.Max())
+			assert.Equal(t, int64(1), s.At(0, 0))
+			assert.Equal(t, int64(3), s.At(3, 3))
+			assert.Equal(t, int64(2), s.At(5, 5))
+			assert.Equal(t, int64(0), s.At(5, 0))
+			assert.Equal(t, int64(0), s.At(0, 9))
+
+			sum, err := s.Sum(makeTestRectangle(t, 0, 9, 0, 9, 0))
+			assert.Nil(t, err)
+			assert.Equal(t, int64(16+2*16), sum)
+
+			sum, err = s.Sum(makeTestRectangle(t, 3, 4, 3, 4, 0))
+			assert.Nil(t, err)
+			assert.Equal(t, int64(3+2+2+2), sum)
+
+			assert.Nil(t, s.Subtract(makeTestRectangle(t, 2, 5, 2, 5, 2)))
+			assert.Nil(t, s.Subtract(makeTestRectangle(t, 0, 3, 0, 3, 1)))
+
+			rectangles, err = s.Rectangles()
+			assert.Nil(t, err)
+			assert.Empty(t, rectangles)
+			assert.Nil(t, s.Validate())
+		})
+	}
+}
+
+func TestSet2DErrors(t *testing.T) {
+	_, err := MakeRectangle(0, 1, 3, 2, 1)
+	assert.True(t, errors.Is(err, ErrInvalidRange))
+
+	s := NewSet2D(NewPersistentTree)
+	assert.True(t, errors.Is(s.Add(rectangle{1, 0, 0, 2, 1}), ErrInvalidRange))
+
+	assert.Nil(t, s.Add(makeTestRectangle(t, 0, 1, 0, 1, 1)))
+	assert.Nil(t, s.Add(makeTestRectangle(t, 0, 1, 2, 3, math.MaxInt64)))
+	before, err := s.Rectangles()
+	assert.Nil(t, err)
+
+	//the rows below 2 take the weight before the rows above overflow
+	assert.True(t, errors.Is(s.Add(makeTestRectangle(t, 0, 0, 0, 5, 1)), ErrWeightOverflow))
+	after, err := s.Rectangles()
+	assert.Nil(t, err)
+	assert.Equal(t, before, after)
+	assert.Nil(t, s.Validate())
+
+	s = NewSet2D(NewPersistentTree)
+	assert.Nil(t, s.Add(makeTestRectangle(t, 0, math.MaxInt64, 0, math.MaxInt64, 2)))
+	_, err = s.Sum(makeTestRectangle(t, 0, math.MaxInt64, 0, math.MaxInt64, 0))
+	assert.True(t, errors.Is(err, ErrWeightOverflow))
+}
+
+func TestSet2DWholePlane(t *testing.T) {
+	for name, newImplementation := range implementationsToTest(t) {
+		t.Run(name, func(t *testing.T) {
+			s := NewSet2D(newImplementation)
+			assert.Nil(t, s.Add(makeTestRectangle(t, math.MinInt64, math.MaxInt64, math.MinInt64, math.MaxInt64, 1)))
+			assert.Nil(t, s.Add(makeTestRectangle(t, -2, 1, math.MinInt64, -1, 2)))
+			assert.Nil(t, s.Validate())
+
+			rectangles, err := s.Rectangles()
+			assert.Nil(t, err)
+			assert.Equal(
+				t,
+				[]rectangle{
+					{math.MinInt64, -3, math.MinInt64, -1, 1},
+					{-2, 1, math.MinInt64, -1, 3},
+					{2, math.MaxInt64, math.MinInt64, -1, 1},
+					{math.MinInt64, math.MaxInt64, 0, math.MaxInt64, 1},
+				},
+				rectangles,
+			)
+
+			assert.Equal(t, int64(3), s.At(-2, math.MinInt64))
+			assert.Equal(t, int64(1), s.At(math.MaxInt64, math.MaxInt64))
+
+			sum, err := s.Sum(makeTestRectangle(t, -3, 2, -1, 0, 0))
+			assert.Nil(t, err)
+			assert.Equal(t, int64(1+4*3+1+6), sum)
+
+			assert.Nil(t, s.Subtract(makeTestRectangle(t, math.MinInt64, math.MaxInt64, math.MinInt64, math.MaxInt64, 1)))
+			assert.Nil(t, s.Subtract(makeTestRectangle(t, -2, 1, math.MinInt64, -1, 2)))
+
+			rectangles, err = s.Rectangles()
+			assert.Nil(t, err)
+			assert.Empty(t, rectangles)
+		})
+	}
+}
+
+func TestSet2DRandom(t *testing.T) {
+	for name, newImplementation := range implementationsToTest(t) {
+		t.Run(name, func(t *testing.T) {
+			random := rand.New(rand.NewSource(1))
+			s := NewSet2D(newImplementation)
+			grid := [20][20]int64{}
+
+			for i := 0; i < 60; i++ {
+				left, bottom := random.Int63n(20), random.Int63n(20)
+				r := makeTestRectangle(
+					t,
+					left,
+					left+random.Int63n(20-left),
+					bottom,
+					bottom+random.Int63n(20-bottom),
+					random.Int63n(7)-3,
+				)
+
+				assert.Nil(t, s.Add(r))
+
+				for y := r.bottom; y <= r.top; y++ {
+					for x := r.left; x <= r.right; x++ {
+						grid[y][x] += r.weight
+					}
+				}
+			}
+
+			assert.Nil(t, s.Validate())
+			max := int64(0)
+
+			for y := range grid {
+				for x, weight := range grid[y] {
+					assert.Equal(t, weight, s.At(int64(x), int64(y)), "%d, %d", x, y)
+
+					if weight > max {
+						max = weight
+					}
+				}
+			}
+
+			assert.Equal(t, max, s.Max())
+
+			for i := 0; i < 20; i++ {
+				left, bottom := random.Int63n(20), random.Int63n(20)
+				region := makeTestRectangle(t, left, left+random.Int63n(20-left), bottom, bottom+random.Int63n(20-bottom), 0)
+				expected := int64(0)
+
+				for y := region.bottom; y <= region.top; y++ {
+					for x := region.left; x <= region.right; x++ {
+						expected += grid[y][x]
+					}
+				}
+
+				sum, err := s.Sum(region)
+				assert.Nil(t, err)
+				assert.Equal(t, expected, sum, "%s", region)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package indexset
 
 import (
+	"errors"
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -84,3 +86,201 @@ func TestAdd(t *testing.T) {
 		)
 	}
 }
+
+func TestAt(t *testing.T) {
+	for implementationName, implementation := range implementationsToTest(t) {
+		t.Run(
+			implementationName,
+			func(t *testing.T) {
+				set := &Set{Implementation: implementation()}
+				assert.Nil(t, set.Add(indexRange{1, 5, 1}))
+				assert.Nil(t, set.Add(indexRange{3, 8, 2}))
+
+				for index, expected := range []int64{0, 1, 1, 3, 3, 3, 2, 2, 2, 0} {
+					assert.Equal(t, expected, set.At(int64(index)), "index %d", index)
+				}
+			},
+		)
+	}
+}
+
+func TestAddNegative(t *testing.T) {
+	for implementationName, implementation := range implementationsToTest(t) {
+		t.Run(
+			implementationName,
+			func(t *testing.T) {
+				set := &Set{Implementation: implementation()}
+				assert.Nil(t, set.Add(indexRange{math.MinInt64, math.MaxInt64, 1}))
+				assert.Nil(t, set.Add(indexRange{-5, -2, 2}))
+				assert.Nil(t, set.Add(indexRange{-3, 4, 1}))
+
+				assert.Equal(
+					t,
+					[]indexRange{
+						{math.MinInt64, -6, 1},
+						{-5, -4, 3},
+						{-3, -2, 4},
+						{-1, 4, 2},
+						{5, math.MaxInt64, 1},
+					},
+					collectIndexRanges(t, set),
+				)
+				assert.Nil(t, set.Validate())
+			},
+		)
+	}
+}
+
+func TestGaps(t *testing.T) {
+	set := &Set{Implementation: NewPersistentTree()}
+	assert.Nil(t, set.Add(indexRange{3, 5, 1}))
+	assert.Nil(t, set.Add(indexRange{8, 8, 1}))
+	assert.Nil(t, set.Add(indexRange{9, 12, 1}))
+	assert.Nil(t, set.Add(indexRange{15, 20, 1}))
+
+	gaps, err := set.Gaps(indexRange{0, 30, 0})
+	assert.Nil(t, err)
+	assert.Equal(t, []indexRange{{0, 2, 0}, {6, 7, 0}, {13, 14, 0}, {21, 30, 0}}, gaps)
+
+	gaps, err = set.Gaps(indexRange{4, 16, 0})
+	assert.Nil(t, err)
+	assert.Equal(t, []indexRange{{6, 7, 0}, {13, 14, 0}}, gaps)
+
+	gaps, err = set.Gaps(indexRange{9, 12, 0})
+	assert.Nil(t, err)
+	assert.Empty(t, gaps)
+
+	assert.Nil(t, set.Add(indexRange{25, math.MaxInt64, 1}))
+
+	gaps, err = set.Gaps(indexRange{0, math.MaxInt64, 0})
+	assert.Nil(t, err)
+	assert.Equal(t, []indexRange{{0, 2, 0}, {6, 7, 0}, {13, 14, 0}, {21, 24, 0}}, gaps)
+}
+
+func TestSubtract(t *testing.T) {
+	for implementationName, implementation := range implementationsToTest(t) {
+		t.Run(
+			implementationName,
+			func(t *testing.T) {
+				set := &Set{Implementation: implementation()}
+				assert.Nil(t, set.Add(indexRange{1, 5, 1}))
+				assert.Nil(t, set.Add(indexRange{3, 8, 2}))
+
+				assert.Nil(t, set.Subtract(indexRange{3, 8, 2}))
+				assert.Equal(t, []indexRange{{1, 2, 1}, {3, 5, 1}}, collectIndexRanges(t, set))
+
+				assert.Nil(t, set.Subtract(indexRange{2, 3, 1}))
+				assert.Equal(t, []indexRange{{1, 1, 1}, {4, 5, 1}}, collectIndexRanges(t, set))
+
+				assert.Nil(t, set.Subtract(indexRange{5, 6, 1}))
+				assert.Equal(t, []indexRange{{1, 1, 1}, {4, 4, 1}, {6, 6, -1}}, collectIndexRanges(t, set))
+				assert.Nil(t, set.Validate())
+			},
+		)
+	}
+}
+
+var errInjected = errors.New("injected")
+
+// failingImplementation fails the failReplace'th call to Replace, or the
+// failAdd'th call to AddOrFindOverlapping, counting from 1. findOverlapping
+// makes AddOrFindOverlapping report a segment that is not there.
+type failingImplementation struct {
+	Implementation
+	failReplace, replaces int
+	failAdd, adds         int
+	findOverlapping       bool
+}
+
+func (f *failingImplementation) Replace(original Member, replacements ...indexRange) error {
+	if f.replaces++; f.replaces == f.failReplace {
+		return errInjected
+	}
+
+	return f.Implementation.Replace(original, replacements...)
+}
+
+func (f *failingImplementation) AddOrFindOverlapping(newRange indexRange) ([]Member, error) {
+	if f.adds++; f.adds != f.failAdd {
+		return f.Implementation.AddOrFindOverlapping(newRange)
+	}
+
+	if f.findOverlapping {
+		return f.Implementation.FindOverlapping(indexRange{math.MinInt64, math.MaxInt64, 0}), nil
+	}
+
+	return nil, errInjected
+}
+
+func TestAddIsAtomic(t *testing.T) {
+	type atomicTestCase struct {
+		description    string
+		segments       []indexRange
+		implementation failingImplementation
+		newRange       indexRange
+		expectedErr    error
+	}
+
+	testCases := []atomicTestCase{
+		{
+			description: "split fails on second segment",
+			segments:    []indexRange{{0, 4, 1}, {5, 9, math.MaxInt64}},
+			newRange:    indexRange{0, 12, 1},
+			expectedErr: ErrWeightOverflow,
+		},
+		{
+			description:    "replace fails on second segment",
+			segments:       []indexRange{{0, 4, 1}, {5, 9, 2}},
+			implementation: failingImplementation{failReplace: 2},
+			newRange:       indexRange{0, 12, 1},
+			expectedErr:    errInjected,
+		},
+		{
+			description:    "replace fails on first segment",
+			segments:       []indexRange{{0, 4, 1}, {5, 9, 2}},
+			implementation: failingImplementation{failReplace: 1},
+			newRange:       indexRange{0, 12, 1},
+			expectedErr:    errInjected,
+		},
+		{
+			description:    "adding carryover fails",
+			segments:       []indexRange{{0, 4, 1}, {5, 9, 2}},
+			implementation: failingImplementation{failAdd: 2},
+			newRange:       indexRange{3, 12, 1},
+			expectedErr:    errInjected,
+		},
+		{
+			description:    "carryover overlaps",
+			segments:       []indexRange{{0, 4, 1}, {5, 9, 2}},
+			implementation: failingImplementation{failAdd: 2, findOverlapping: true},
+			newRange:       indexRange{3, 12, 1},
+			expectedErr:    ErrImpossibleState,
+		},
+	}
+
+	for name, newImplementation := range implementationsToTest(t) {
+		for _, test := range testCases {
+			t.Run(
+				name+"/"+test.description,
+				func(t *testing.T) {
+					set := &Set{Implementation: newImplementation()}
+
+					for _, r := range test.segments {
+						assert.Nil(t, set.Add(r))
+					}
+
+					before := collectIndexRanges(t, set)
+
+					implementation := test.implementation
+					implementation.Implementation = set.Implementation
+					set.Implementation = &implementation
+
+					err := set.Add(test.newRange)
+					assert.True(t, errors.Is(err, test.expectedErr), "%v", err)
+					assert.Equal(t, before, collectIndexRanges(t, set))
+					assert.Nil(t, set.Validate())
+				},
+			)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package indexset
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+type shard struct {
+	sync.Mutex
+	left  int64
+	right int64
+	set   Set
+}
+
+// ShardedSet partitions the coordinate space into contiguous shards, each
+// with its own Implementation and lock, so that Adds landing in different
+// shards can proceed in parallel. Ranges spanning a shard boundary are split
+// at the boundary, so segments are never merged across shards.
+type ShardedSet struct {
+	shards []*shard
+}
+
+// NewShardedSet splits [0, upper] into count shards whose widths differ by
+// at most one. The first shard also covers every negative index and the last
+// everything past upper.
+func NewShardedSet(upper int64, count int, newImplementation func() Implementation) (*ShardedSet, error) {
+	if count < 1 {
+		return nil, fmt.Errorf("%w: %d is less than 1", ErrInvalidShardCount, count)
+	}
+
+	if upper < int64(count)-1 {
+		return nil, fmt.Errorf("%w: %d shards do not fit below %d", ErrInvalidShardCount, count, upper)
+	}
+
+	//upper+1 indices do not always fit an int64, so the first remainder+1
+	//shards take one index more than width instead
+	width, remainder := upper/int64(count), upper%int64(count)
+	s := &ShardedSet{shards: make([]*shard, count)}
+
+	for i := range s.shards {
+		left := int64(i) * width
+
+		if int64(i) <= remainder {
+			left += int64(i)
+		} else {
+			left += remainder + 1
+		}
+
+		s.shards[i] = &shard{left: left, set: Set{Implementation: newImplementation()}}
+
+		if i > 0 {
+			s.shards[i-1].right = left - 1
+		}
+	}
+
+	s.shards[0].left = math.MinInt64
+	s.shards[count-1].right = math.MaxInt64
+
+	return s, nil
+}
+
+func (s *ShardedSet) shardIndex(coordinate int64) int {
+	return sort.Search(len(s.shards), func(i int) bool { return s.shards[i].right >= coordinate })
+}
+
+// Add is safe to call from multiple goroutines. A range spanning several
+// shards is added to each of them while all of them are locked. If that
+// fails in any shard, every shard is left as it was, but concurrent readers
+// visiting one shard at a time may still observe the range in some shards
+// and not yet in others.
+func (s *ShardedSet) Add(newRange indexRange) error {
+	if _, err := MakeRange(newRange.left, newRange.right, newRange.weight); err != nil {
+		return err
+	}
+
+	first, last := s.shardIndex(newRange.left), s.shardIndex(newRange.right)
+	shards := s.shards[first : last+1]
+
+	//shards are always locked in order, so Adds cannot deadlock
+	for _, sh := range shards {
+		sh.Lock()
+		defer sh.Unlock()
+	}
+
+	transactions := make([]*Transaction, 0, len(shards))
+
+	for _, sh := range shards {
+		piece := newRange
+
+		if piece.left < sh.left {
+			piece.left = sh.left
+		}
+
+		if piece.right > sh.right {
+			piece.right = sh.right
+		}
+
+		tx := sh.set.Begin()
+		transactions = append(transactions, tx)
+
+		if err := tx.Add(piece); err != nil {
+			for i := len(transactions) - 1; i >= 0; i-- {
+				transactions[i].Rollback()
+			}
+
+			return err
+		}
+	}
+
+	for _, tx := range transactions {
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Do iterates every shard in coordinate order, holding each shard's lock
+// while its segments are visited. f must not call Add.
+func (s *ShardedSet) Do(f func(Member) (stop bool)) error {
+	for _, sh := range s.shards {
+		stopped := false
+
+		sh.Lock()
+		err := sh.set.Do(
+			func(m Member) bool {
+				stopped = f(m)
+				return stopped
+			},
+		)
+		sh.Unlock()
+
+		if err != nil {
+			return err
+		}
+
+		if stopped {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Validate validates every shard and checks that no segment lies outside
+// the bounds of its shard.
+func (s *ShardedSet) Validate() error {
+	for _, sh := range s.shards {
+		var outside error
+
+		sh.Lock()
+		err := sh.set.Validate()
+
+		if err == nil {
+			err = sh.set.Do(
+				func(m Member) bool {
+					r := m.IndexRange()
+
+					if r.left < sh.left || r.right > sh.right {
+						outside = &RangeError{Op: "validate", Ranges: []indexRange{r}, Err: ErrOutOfOrder}
+						return true
+					}
+
+					return false
+				},
+			)
+		}
+
+		sh.Unlock()
+
+		if err != nil {
+			return err
+		}
+
+		if outside != nil {
+			return outside
+		}
+	}
+
+	return nil
+}
+
+// Nth returns the n-th segment across all shards, stopping at a corrupted
+// segment as Set.Nth does.
+func (s *ShardedSet) Nth(n int) Member {
+	i := 0
+	var found Member
+
+	s.Do(
+		func(m Member) bool {
+			if i == n {
+				found = m
+				return true
+			}
+
+			i++
+			return false
+		},
+	)
+
+	return found
+}
+
+func (s *ShardedSet) Max() int64 {
+	max := int64(0)
+
+	for _, sh := range s.shards {
+		sh.Lock()
+		weight := sh.set.Max()
+		sh.Unlock()
+
+		if weight > max {
+			max = weight
+		}
+	}
+
+	return max
+}
@@ -0,0 +1,130 @@
+package indexset
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func collectShardedIndexRanges(s *ShardedSet) []indexRange {
+	ranges := make([]indexRange, 0)
+
+	s.Do(
+		func(m Member) bool {
+			ranges = append(ranges, m.IndexRange())
+			return false
+		},
+	)
+
+	return ranges
+}
+
+func TestShardedSetSplitsAtBoundaries(t *testing.T) {
+	set, err := NewShardedSet(39, 4, NewPersistentTree)
+	assert.Nil(t, err)
+
+	assert.Nil(t, set.Add(indexRange{25, 40, 2}))
+	assert.Nil(t, set.Add(indexRange{5, 12, 1}))
+	assert.Nil(t, set.Add(indexRange{1, 3, 7}))
+
+	assert.Equal(
+		t,
+		[]indexRange{
+			{1, 3, 7},
+			{5, 9, 1},
+			{10, 12, 1},
+			{25, 29, 2},
+			{30, 40, 2},
+		},
+		collectShardedIndexRanges(set),
+	)
+
+	assert.Equal(t, int64(7), set.Max())
+	assert.Equal(t, indexRange{10, 12, 1}, set.Nth(2).IndexRange())
+	assert.Nil(t, set.Nth(5))
+}
+
+func TestShardedSetNegative(t *testing.T) {
+	set, err := NewShardedSet(19, 2, NewPersistentTree)
+	assert.Nil(t, err)
+
+	assert.Nil(t, set.Add(indexRange{-20, 12, 1}))
+	assert.Equal(t, []indexRange{{-20, 9, 1}, {10, 12, 1}}, collectShardedIndexRanges(set))
+	assert.Nil(t, set.Validate())
+}
+
+func TestShardedSetWholeRange(t *testing.T) {
+	for _, count := range []int{1, 2, 3} {
+		set, err := NewShardedSet(math.MaxInt64, count, NewPersistentTree)
+		assert.Nil(t, err)
+
+		assert.Nil(t, set.Add(indexRange{math.MaxInt64, math.MaxInt64, 1}))
+		assert.Nil(t, set.Add(indexRange{0, 0, 1}))
+		assert.Len(t, collectShardedIndexRanges(set), 2, "%d shards", count)
+		assert.Nil(t, set.Validate())
+	}
+
+	set, err := NewShardedSet(math.MaxInt64, 2, NewPersistentTree)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(math.MaxInt64/2), set.shards[0].right)
+	assert.Equal(t, int64(math.MaxInt64/2+1), set.shards[1].left)
+}
+
+func TestShardedSetAddIsAtomic(t *testing.T) {
+	set, err := NewShardedSet(29, 3, NewPersistentTree)
+	assert.Nil(t, err)
+
+	assert.Nil(t, set.Add(indexRange{25, 26, math.MaxInt64}))
+	before := collectShardedIndexRanges(set)
+
+	//the pieces in the first two shards fit, the one in the last overflows
+	assert.True(t, errors.Is(set.Add(indexRange{5, 25, 1}), ErrWeightOverflow))
+	assert.Equal(t, before, collectShardedIndexRanges(set))
+	assert.Nil(t, set.Validate())
+}
+
+func TestShardedSetInvalid(t *testing.T) {
+	_, err := NewShardedSet(10, 0, NewPersistentTree)
+	assert.True(t, errors.Is(err, ErrInvalidShardCount))
+
+	_, err = NewShardedSet(1, 3, NewPersistentTree)
+	assert.True(t, errors.Is(err, ErrInvalidShardCount))
+
+	set, err := NewShardedSet(10, 2, NewPersistentTree)
+	assert.Nil(t, err)
+	assert.True(t, errors.Is(set.Add(indexRange{5, 1, 1}), ErrInvalidRange))
+}
+
+func TestShardedSetParallelAdd(t *testing.T) {
+	set, err := NewShardedSet(999, 4, NewPersistentTree)
+	assert.Nil(t, err)
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+
+		go func(w int) {
+			defer wg.Done()
+
+			for i := w; i < 100; i += 8 {
+				left := int64(i * 10)
+				assert.Nil(t, set.Add(indexRange{left, left + 4, int64(i + 1)}))
+			}
+		}(w)
+	}
+
+	wg.Wait()
+
+	ranges := collectShardedIndexRanges(set)
+	assert.Len(t, ranges, 100)
+
+	for i, r := range ranges {
+		assert.Equal(t, int64(i*10), r.left)
+	}
+
+	assert.Equal(t, int64(100), set.Max())
+}
@@ -0,0 +1,74 @@
+package indexset
+
+import (
+	"math"
+	"sort"
+)
+
+// Stats summarizes the segments of a Set. Lengths and counts saturate at
+// math.MaxUint64.
+type Stats struct {
+	Segments int
+	// Covered is the number of indices some segment covers.
+	Covered uint64
+	// MeanWeight is the mean weight of the covered indices, or 0 when none
+	// are covered.
+	MeanWeight float64
+	// Histogram maps every weight to the number of indices that have it.
+	Histogram map[int64]uint64
+}
+
+// Stats computes the Stats of s in a single pass over its segments.
+func (s *Set) Stats() (Stats, error) {
+	stats := Stats{Histogram: make(map[int64]uint64)}
+	sum := float64(0)
+
+	err := s.Do(
+		func(m Member) bool {
+			r := m.IndexRange()
+
+			stats.Segments++
+			stats.Covered = r.addLength(stats.Covered)
+			stats.Histogram[r.weight] = r.addLength(stats.Histogram[r.weight])
+			sum += float64(r.weight) * (float64(uint64(r.right-r.left)) + 1)
+
+			return false
+		},
+	)
+
+	if err != nil {
+		return Stats{}, err
+	}
+
+	if stats.Covered > 0 {
+		stats.MeanWeight = sum / float64(stats.Covered)
+	}
+
+	return stats, nil
+}
+
+// Percentile returns the smallest weight that at least p percent of the
+// covered indices are at or below, with p clamped to [0, 100]. It returns 0
+// when no index is covered.
+func (stats Stats) Percentile(p float64) int64 {
+	weights := make([]int64, 0, len(stats.Histogram))
+
+	for weight := range stats.Histogram {
+		weights = append(weights, weight)
+	}
+
+	sort.Slice(weights, func(i, j int) bool { return weights[i] < weights[j] })
+
+	rank := math.Ceil(math.Max(0, math.Min(p, 100)) / 100 * float64(stats.Covered))
+	seen := float64(0)
+
+	for _, weight := range weights {
+		seen += float64(stats.Histogram[weight])
+
+		if seen >= rank {
+			return weight
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,62 @@
+package indexset
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	for name, newImplementation := range implementationsToTest(t) {
+		t.Run(name, func(t *testing.T) {
+			s := &Set{Implementation: newImplementation()}
+
+			for _, r := range []indexRange{{0, 3, 2}, {2, 5, 1}, {8, 9, 4}, {10, 12, -1}} {
+				assert.Nil(t, s.Add(r))
+			}
+
+			stats, err := s.Stats()
+			assert.Nil(t, err)
+			assert.Equal(t, 5, stats.Segments)
+			assert.Equal(t, uint64(11), stats.Covered)
+			assert.InDelta(t, 17.0/11, stats.MeanWeight, 1e-9)
+			assert.Equal(t, map[int64]uint64{-1: 3, 1: 2, 2: 2, 3: 2, 4: 2}, stats.Histogram)
+
+			for p, expected := range map[float64]int64{-5: -1, 0: -1, 27: -1, 28: 1, 50: 2, 90: 4, 100: 4, 200: 4} {
+				assert.Equal(t, expected, stats.Percentile(p), "p %v", p)
+			}
+		})
+	}
+}
+
+func TestStatsEmpty(t *testing.T) {
+	stats, err := (&Set{Implementation: NewLinkedList()}).Stats()
+	assert.Nil(t, err)
+	assert.Equal(t, Stats{Histogram: map[int64]uint64{}}, stats)
+	assert.Equal(t, int64(0), stats.Percentile(50))
+}
+
+func TestStatsSaturates(t *testing.T) {
+	s := &Set{Implementation: NewPersistentTree()}
+	assert.Nil(t, s.Add(indexRange{math.MinInt64, -1, 2}))
+	assert.Nil(t, s.Add(indexRange{0, math.MaxInt64, 2}))
+
+	stats, err := s.Stats()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(math.MaxUint64), stats.Covered)
+	assert.Equal(t, map[int64]uint64{2: math.MaxUint64}, stats.Histogram)
+	assert.InDelta(t, 2, stats.MeanWeight, 1e-9)
+	assert.Equal(t, int64(2), stats.Percentile(50))
+}
+
+func TestStatsMeanOfWideSegments(t *testing.T) {
+	for _, r := range []indexRange{{-10, math.MaxInt64, 5}, {math.MinInt64, math.MaxInt64, 5}} {
+		s := &Set{Implementation: NewLinkedList()}
+		assert.Nil(t, s.Add(r))
+
+		stats, err := s.Stats()
+		assert.Nil(t, err)
+		assert.InDelta(t, 5, stats.MeanWeight, 1e-9, "%s", r)
+	}
+}
@@ -0,0 +1,155 @@
+package indexset
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	svgDefaultWidth  = 640
+	svgDefaultHeight = 240
+	svgMarginLeft    = 50
+	svgMarginRight   = 10
+	svgMarginTop     = 10
+	svgMarginBottom  = 20
+)
+
+// SVGOptions controls how WriteSVG draws a Set.
+type SVGOptions struct {
+	// Width and Height are the size of the image in pixels, 640 by 240 by
+	// default.
+	Width  int
+	Height int
+	// Window limits the coordinates drawn. By default it spans from the first
+	// to the last segment.
+	Window *indexRange
+}
+
+// WriteSVG draws s as a step chart of weight over coordinate. Each segment
+// spans from its left coordinate to just before right+1, so adjacent
+// segments meet without a gap. The y axis runs from the smallest weight or 0
+// up to the largest weight. An empty set without a window is drawn over
+// [0, 0].
+func (s *Set) WriteSVG(w io.Writer, options SVGOptions) error {
+	window, err := s.renderWindow(options.Window)
+
+	if err != nil {
+		return err
+	}
+
+	if window == nil {
+		window = &indexRange{}
+	}
+
+	width, height := options.Width, options.Height
+
+	if width <= 0 {
+		width = svgDefaultWidth
+	}
+
+	if height <= 0 {
+		height = svgDefaultHeight
+	}
+
+	segments := make([]indexRange, 0)
+	min, max := int64(0), int64(0)
+
+	for _, m := range s.FindOverlapping(*window) {
+		r := m.IndexRange()
+
+		if r.left < window.left {
+			r.left = window.left
+		}
+
+		if r.right > window.right {
+			r.right = window.right
+		}
+
+		if r.weight < min {
+			min = r.weight
+		}
+
+		if r.weight > max {
+			max = r.weight
+		}
+
+		segments = append(segments, r)
+	}
+
+	plotWidth := float64(width - svgMarginLeft - svgMarginRight)
+	plotHeight := float64(height - svgMarginTop - svgMarginBottom)
+	span := float64(uint64(window.right-window.left)) + 1
+	top := max
+
+	//with no weights to scale by keep the x axis at the bottom
+	if top == min {
+		top = min + 1
+	}
+
+	//x takes the offset from window.left so huge coordinates keep precision.
+	//Offsets are unsigned, since a window can be wider than MaxInt64
+	x := func(offset float64) float64 {
+		return svgMarginLeft + offset/span*plotWidth
+	}
+
+	//in float64, since top-min overflows for extreme weights
+	y := func(weight int64) float64 {
+		return svgMarginTop + (float64(top)-float64(weight))/(float64(top)-float64(min))*plotHeight
+	}
+
+	sb := strings.Builder{}
+
+	fmt.Fprintf(
+		&sb,
+		"<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" font-family=\"monospace\" font-size=\"10\">\n",
+		width, height, width, height,
+	)
+
+	fmt.Fprintf(&sb, "<path fill=\"#cfe0f5\" stroke=\"#2a62a8\" d=\"M%.1f %.1f", x(0), y(0))
+
+	for _, r := range segments {
+		fmt.Fprintf(
+			&sb,
+			" H%.1f V%.1f H%.1f V%.1f",
+			x(float64(uint64(r.left-window.left))),
+			y(r.weight),
+			x(float64(uint64(r.right-window.left))+1),
+			y(0),
+		)
+	}
+
+	fmt.Fprintf(&sb, " H%.1f Z\"/>\n", x(span))
+
+	//axes
+	fmt.Fprintf(&sb, "<path fill=\"none\" stroke=\"#000\" d=\"M%.1f %.1f V%.1f M%.1f %.1f H%.1f\"/>\n",
+		x(0), y(max), y(min), x(0), y(0), x(span),
+	)
+
+	//y labels, min <= 0 <= max
+	labels := []int64{max}
+
+	if max != 0 {
+		labels = append(labels, 0)
+	}
+
+	if min != 0 {
+		labels = append(labels, min)
+	}
+
+	for _, weight := range labels {
+		fmt.Fprintf(&sb, "<text x=\"%.1f\" y=\"%.1f\" text-anchor=\"end\" dominant-baseline=\"middle\">%d</text>\n",
+			x(0)-4, y(weight), weight,
+		)
+	}
+
+	//x labels
+	labelY := float64(height - svgMarginBottom + 14)
+	fmt.Fprintf(&sb, "<text x=\"%.1f\" y=\"%.1f\" text-anchor=\"start\">%d</text>\n", x(0), labelY, window.left)
+	fmt.Fprintf(&sb, "<text x=\"%.1f\" y=\"%.1f\" text-anchor=\"end\">%d</text>\n", x(span), labelY, window.right)
+
+	sb.WriteString("</svg>\n")
+
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
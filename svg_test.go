@@ -0,0 +1,58 @@
+package indexset
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteSVG(t *testing.T) {
+	expected := "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"160\" height=\"70\" viewBox=\"0 0 160 70\" font-family=\"monospace\" font-size=\"10\">\n" +
+		"<path fill=\"#cfe0f5\" stroke=\"#2a62a8\" d=\"M50.0 50.0 H50.0 V30.0 H70.0 V50.0 H70.0 V10.0 H90.0 V50.0 H90.0 V30.0 H110.0 V50.0 H130.0 V40.0 H150.0 V50.0 H150.0 Z\"/>\n" +
+		"<path fill=\"none\" stroke=\"#000\" d=\"M50.0 10.0 V50.0 M50.0 50.0 H150.0\"/>\n" +
+		"<text x=\"46.0\" y=\"10.0\" text-anchor=\"end\" dominant-baseline=\"middle\">4</text>\n" +
+		"<text x=\"46.0\" y=\"50.0\" text-anchor=\"end\" dominant-baseline=\"middle\">0</text>\n" +
+		"<text x=\"50.0\" y=\"64.0\" text-anchor=\"start\">0</text>\n" +
+		"<text x=\"150.0\" y=\"64.0\" text-anchor=\"end\">9</text>\n" +
+		"</svg>\n"
+
+	for name, newImplementation := range implementationsToTest(t) {
+		t.Run(name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			assert.Nil(t, renderTestSet(t, newImplementation).WriteSVG(out, SVGOptions{Width: 160, Height: 70}))
+			assert.Equal(t, expected, out.String())
+		})
+	}
+}
+
+func TestWriteSVGEmpty(t *testing.T) {
+	out := &bytes.Buffer{}
+	s := &Set{Implementation: NewLinkedList()}
+
+	assert.Nil(t, s.WriteSVG(out, SVGOptions{}))
+	assert.Contains(t, out.String(), "<path fill=\"#cfe0f5\" stroke=\"#2a62a8\" d=\"M50.0 220.0 H630.0 Z\"/>\n")
+	assert.Contains(t, out.String(), "</svg>\n")
+}
+
+func TestWriteSVGWholeRange(t *testing.T) {
+	out := &bytes.Buffer{}
+	s := &Set{Implementation: NewLinkedList()}
+	assert.Nil(t, s.Add(indexRange{math.MinInt64, math.MaxInt64, 1}))
+	assert.Nil(t, s.Add(indexRange{0, math.MaxInt64, 1}))
+
+	assert.Nil(t, s.WriteSVG(out, SVGOptions{Width: 160, Height: 70}))
+	assert.Contains(t, out.String(), "d=\"M50.0 50.0 H50.0 V30.0 H100.0 V50.0 H100.0 V10.0 H150.0 V50.0 H150.0 Z\"")
+}
+
+func TestWriteSVGExtremeWeights(t *testing.T) {
+	out := &bytes.Buffer{}
+	s := &Set{Implementation: NewLinkedList()}
+	assert.Nil(t, s.Add(indexRange{0, 4, math.MaxInt64}))
+	assert.Nil(t, s.Add(indexRange{5, 9, -1}))
+
+	assert.Nil(t, s.WriteSVG(out, SVGOptions{Width: 160, Height: 70}))
+	assert.Contains(t, out.String(), "d=\"M50.0 50.0 H50.0 V10.0 H100.0 V50.0 H100.0 V50.0 H150.0 V50.0 H150.0 Z\"")
+	assert.Contains(t, out.String(), "d=\"M50.0 10.0 V50.0 M50.0 50.0 H150.0\"")
+}
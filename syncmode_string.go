@@ -0,0 +1,26 @@
+// Code generated by "stringer -type=SyncMode -trimprefix=SyncMode"; DO NOT EDIT.
+
+package indexset
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[SyncModeAlways-0]
+	_ = x[SyncModeInterval-1]
+	_ = x[SyncModeNever-2]
+}
+
+const _SyncMode_name = "AlwaysIntervalNever"
+
+var _SyncMode_index = [...]uint8{0, 6, 14, 19}
+
+func (i SyncMode) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_SyncMode_index)-1 {
+		return "SyncMode(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _SyncMode_name[_SyncMode_index[idx]:_SyncMode_index[idx+1]]
+}
@@ -0,0 +1,102 @@
+package indexset
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReadRanges parses ranges written one per line as "left right weight",
+// which is the format of testfile1.txt. An optional first line of two
+// numbers, "size count", is treated as a header and count ranges must
+// follow it. Blank lines are skipped.
+func ReadRanges(r io.Reader) ([]indexRange, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	ranges := make([]indexRange, 0)
+	expected := -1
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		fields := strings.Fields(scanner.Text())
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		numbers := make([]int64, len(fields))
+
+		for i, field := range fields {
+			number, err := strconv.ParseInt(field, 10, 64)
+
+			if err != nil {
+				return ranges, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+
+			numbers[i] = number
+		}
+
+		switch {
+		case len(numbers) == 2 && expected == -1 && len(ranges) == 0:
+			expected = int(numbers[1])
+
+		case len(numbers) == 3:
+			r, err := MakeRange(numbers[0], numbers[1], numbers[2])
+
+			if err != nil {
+				return ranges, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+
+			ranges = append(ranges, *r)
+
+		default:
+			return ranges, fmt.Errorf("line %d: expected \"left right weight\", got %q", lineNumber, scanner.Text())
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return ranges, err
+	}
+
+	if expected != -1 && expected != len(ranges) {
+		return ranges, fmt.Errorf("header declares %d ranges but %d were read", expected, len(ranges))
+	}
+
+	return ranges, nil
+}
+
+// WriteRanges writes the segments of s in the format read by ReadRanges,
+// preceded by a "size count" header where size is the largest right.
+func WriteRanges(w io.Writer, s *Set) error {
+	segments := make([]indexRange, 0)
+
+	err := s.Do(
+		func(m Member) bool {
+			segments = append(segments, m.IndexRange())
+			return false
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	size := int64(0)
+
+	if len(segments) > 0 {
+		size = segments[len(segments)-1].right
+	}
+
+	writer := bufio.NewWriter(w)
+	fmt.Fprintf(writer, "%d %d\n", size, len(segments))
+
+	for _, r := range segments {
+		fmt.Fprintf(writer, "%d %d %d\n", r.left, r.right, r.weight)
+	}
+
+	return writer.Flush()
+}
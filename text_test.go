@@ -0,0 +1,67 @@
+package indexset
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadRanges(t *testing.T) {
+	ranges, err := ReadRanges(strings.NewReader("10 2\n1 5 3\n\n4 8 7\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, []indexRange{{1, 5, 3}, {4, 8, 7}}, ranges)
+
+	ranges, err = ReadRanges(strings.NewReader("1 5 3\n4 8 7"))
+	assert.Nil(t, err)
+	assert.Equal(t, []indexRange{{1, 5, 3}, {4, 8, 7}}, ranges)
+}
+
+func TestReadRangesTestfile(t *testing.T) {
+	file, err := os.Open("testfile1.txt")
+	assert.Nil(t, err)
+	defer file.Close()
+
+	ranges, err := ReadRanges(file)
+	assert.Nil(t, err)
+	assert.Len(t, ranges, 30)
+
+	set := &Set{Implementation: NewPersistentTree()}
+
+	for _, r := range ranges {
+		assert.Nil(t, set.Add(r))
+	}
+
+	assert.Equal(t, int64(8628), set.Max())
+}
+
+func TestReadRangesErrors(t *testing.T) {
+	_, err := ReadRanges(strings.NewReader("10 3\n1 5 3\n"))
+	assert.NotNil(t, err)
+
+	_, err = ReadRanges(strings.NewReader("1 5 x\n"))
+	assert.NotNil(t, err)
+
+	_, err = ReadRanges(strings.NewReader("1 5\n1 5 1\n1 2\n"))
+	assert.NotNil(t, err)
+
+	_, err = ReadRanges(strings.NewReader("5 1 1\n"))
+	assert.True(t, errors.Is(err, ErrLeftLargerThanRight))
+}
+
+func TestWriteRanges(t *testing.T) {
+	set := &Set{Implementation: NewLinkedList()}
+	assert.Nil(t, set.Add(indexRange{1, 5, 1}))
+	assert.Nil(t, set.Add(indexRange{3, 8, 2}))
+
+	var buffer bytes.Buffer
+	assert.Nil(t, WriteRanges(&buffer, set))
+	assert.Equal(t, "8 3\n1 2 1\n3 5 3\n6 8 2\n", buffer.String())
+
+	ranges, err := ReadRanges(&buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, collectIndexRanges(t, set), ranges)
+}
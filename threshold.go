@@ -0,0 +1,79 @@
+package indexset
+
+import (
+	"fmt"
+	"math"
+)
+
+// Above returns the maximal ranges of covered indices whose weight is at
+// least k, with a weight of 0. Indices no segment covers are never included,
+// even when k is 0 or less.
+func (s *Set) Above(k int64) ([]indexRange, error) {
+	return s.Between(k, math.MaxInt64)
+}
+
+// Below returns the maximal ranges of covered indices whose weight is at
+// most k, with a weight of 0.
+func (s *Set) Below(k int64) ([]indexRange, error) {
+	return s.Between(math.MinInt64, k)
+}
+
+// Between returns the maximal ranges of covered indices whose weight is in
+// [lo, hi], with a weight of 0. Adjacent segments that both qualify are
+// merged into one range.
+func (s *Set) Between(lo, hi int64) ([]indexRange, error) {
+	if lo > hi {
+		return nil, fmt.Errorf("%w: threshold %d is above %d", ErrInvalidRange, lo, hi)
+	}
+
+	ranges := make([]indexRange, 0)
+
+	err := s.Do(
+		func(m Member) bool {
+			r := m.IndexRange()
+
+			if r.weight < lo || r.weight > hi {
+				return false
+			}
+
+			if last := len(ranges) - 1; last >= 0 && ranges[last].right+1 == r.left {
+				ranges[last].right = r.right
+			} else {
+				ranges = append(ranges, indexRange{r.left, r.right, 0})
+			}
+
+			return false
+		},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ranges, nil
+}
+
+// CountAbove returns the number of covered indices whose weight is at least
+// k. The count saturates at math.MaxUint64, one short of every int64.
+func (s *Set) CountAbove(k int64) (uint64, error) {
+	count := uint64(0)
+
+	err := s.Do(
+		func(m Member) bool {
+			r := m.IndexRange()
+
+			if r.weight < k {
+				return false
+			}
+
+			count = r.addLength(count)
+			return false
+		},
+	)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,61 @@
+package indexset
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThresholds(t *testing.T) {
+	for name, newImplementation := range implementationsToTest(t) {
+		t.Run(name, func(t *testing.T) {
+			s := &Set{Implementation: newImplementation()}
+
+			for _, r := range []indexRange{{0, 3, 2}, {2, 5, 1}, {8, 9, 4}, {10, 12, -1}} {
+				assert.Nil(t, s.Add(r))
+			}
+
+			//segments are {0,1,2}, {2,3,3}, {4,5,1}, {8,9,4} and {10,12,-1}
+			above, err := s.Above(2)
+			assert.Nil(t, err)
+			assert.Equal(t, []indexRange{{0, 3, 0}, {8, 9, 0}}, above)
+
+			below, err := s.Below(1)
+			assert.Nil(t, err)
+			assert.Equal(t, []indexRange{{4, 5, 0}, {10, 12, 0}}, below)
+
+			between, err := s.Between(1, 3)
+			assert.Nil(t, err)
+			assert.Equal(t, []indexRange{{0, 5, 0}}, between)
+
+			between, err = s.Between(5, 10)
+			assert.Nil(t, err)
+			assert.Equal(t, []indexRange{}, between)
+
+			_, err = s.Between(3, 1)
+			assert.True(t, errors.Is(err, ErrInvalidRange))
+
+			for k, expected := range map[int64]uint64{math.MinInt64: 11, 0: 8, 2: 6, 4: 2, 5: 0} {
+				count, err := s.CountAbove(k)
+				assert.Nil(t, err)
+				assert.Equal(t, expected, count, "k %d", k)
+			}
+		})
+	}
+}
+
+func TestCountAboveSaturates(t *testing.T) {
+	s := &Set{Implementation: NewPersistentTree()}
+	assert.Nil(t, s.Add(indexRange{math.MinInt64, -1, 1}))
+	assert.Nil(t, s.Add(indexRange{0, math.MaxInt64, 1}))
+
+	count, err := s.CountAbove(1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(math.MaxUint64), count)
+
+	above, err := s.Above(1)
+	assert.Nil(t, err)
+	assert.Equal(t, []indexRange{{math.MinInt64, math.MaxInt64, 0}}, above)
+}
@@ -0,0 +1,336 @@
+package indexset
+
+import (
+	"fmt"
+)
+
+// journalEntry records one change to an Implementation: removed was taken
+// out, if hasRemoved, and inserted were put in its place.
+type journalEntry struct {
+	removed    indexRange
+	hasRemoved bool
+	inserted   []indexRange
+	//removedIDs are the contributors removed had in the Provenance
+	removedIDs []string
+}
+
+// journal wraps an Implementation and records every change made through it,
+// so that the changes can be undone in reverse order.
+type journal struct {
+	Implementation
+	entries    []journalEntry
+	provenance *Provenance
+}
+
+func (j *journal) Replace(original Member, replacements ...indexRange) error {
+	removed := original.IndexRange()
+
+	if err := j.Implementation.Replace(original, replacements...); err != nil {
+		return err
+	}
+
+	j.entries = append(
+		j.entries,
+		journalEntry{
+			removed:    removed,
+			hasRemoved: true,
+			inserted:   append([]indexRange(nil), replacements...),
+			removedIDs: j.provenance.idsOf(removed),
+		},
+	)
+
+	return nil
+}
+
+func (j *journal) AddOrFindOverlapping(newRange indexRange) ([]Member, error) {
+	overlapping, err := j.Implementation.AddOrFindOverlapping(newRange)
+
+	if err == nil && len(overlapping) == 0 {
+		j.entries = append(j.entries, journalEntry{inserted: []indexRange{newRange}})
+	}
+
+	return overlapping, err
+}
+
+func (j *journal) beginBatch() {
+	if batcher, ok := j.Implementation.(batcher); ok {
+		batcher.beginBatch()
+	}
+}
+
+func (j *journal) endBatch() {
+	if batcher, ok := j.Implementation.(batcher); ok {
+		batcher.endBatch()
+	}
+}
+
+// undoJournal reverts entries on implementation, newest first, and gives the
+// segments it restores back their contributors in provenance.
+func undoJournal(implementation Implementation, entries []journalEntry, provenance *Provenance) error {
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+
+		for _, r := range entry.inserted {
+			member, err := findExact(implementation, r)
+
+			if err != nil {
+				return err
+			}
+
+			if err := implementation.Replace(member); err != nil {
+				return err
+			}
+
+			provenance.set(r, nil)
+		}
+
+		if !entry.hasRemoved {
+			continue
+		}
+
+		overlapping, err := implementation.AddOrFindOverlapping(entry.removed)
+
+		if err != nil {
+			return err
+		}
+
+		if len(overlapping) > 0 {
+			return &RangeError{
+				Op:     "undo",
+				Ranges: []indexRange{entry.removed, overlapping[0].IndexRange()},
+				Err:    ErrImpossibleState,
+			}
+		}
+
+		provenance.set(entry.removed, entry.removedIDs)
+	}
+
+	return nil
+}
+
+func findExact(implementation Implementation, r indexRange) (Member, error) {
+	for _, m := range implementation.FindOverlapping(r) {
+		if m.IndexRange() == r {
+			return m, nil
+		}
+	}
+
+	return nil, &RangeError{Op: "undo", Ranges: []indexRange{r}, Err: ErrMemberNotFound}
+}
+
+// Transaction groups changes to a Set so they can be kept or reverted
+// together.
+type Transaction struct {
+	set     *Set
+	journal *journal
+	done    bool
+}
+
+// Begin starts a transaction on s. Until it is committed or rolled back
+// every change to s belongs to it, including Adds made on s directly, and
+// snapshots of s do not see any of them. Transactions nest but must be
+// finished in the reverse order they were begun.
+func (s *Set) Begin() *Transaction {
+	if batcher, ok := s.Implementation.(batcher); ok {
+		batcher.beginBatch()
+	}
+
+	j := &journal{Implementation: s.writer(), provenance: s.Provenance}
+	s.journals = append(s.journals, j)
+
+	return &Transaction{set: s, journal: j}
+}
+
+func (s *Set) inTransaction() bool {
+	for _, j := range s.journals {
+		if _, ok := j.(*journal); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writer returns the Implementation that changes to s are made through.
+func (s *Set) writer() Implementation {
+	if len(s.journals) == 0 {
+		return s.Implementation
+	}
+
+	return s.journals[len(s.journals)-1]
+}
+
+func (s *Set) popJournal() {
+	s.journals = s.journals[:len(s.journals)-1]
+}
+
+// Replace calls Replace on the Implementation of s, through any open
+// transaction so that rolling it back reverts the Replace too.
+func (s *Set) Replace(original Member, replacements ...indexRange) error {
+	return s.writer().Replace(original, replacements...)
+}
+
+// AddOrFindOverlapping calls AddOrFindOverlapping on the Implementation of
+// s, through any open transaction like Replace.
+func (s *Set) AddOrFindOverlapping(newRange indexRange) ([]Member, error) {
+	return s.writer().AddOrFindOverlapping(newRange)
+}
+
+// Add adds newRange to the set. If it fails, whatever part of it had been
+// applied is undone and the transaction can go on.
+func (tx *Transaction) Add(newRange indexRange) error {
+	return tx.apply(func() error { return tx.set.Add(newRange) })
+}
+
+// Subtract subtracts oldRange from the set, undoing any part of it that was
+// applied if it fails.
+func (tx *Transaction) Subtract(oldRange indexRange) error {
+	return tx.apply(func() error { return tx.set.Subtract(oldRange) })
+}
+
+func (tx *Transaction) apply(f func() error) error {
+	if err := tx.check(); err != nil {
+		return err
+	}
+
+	mark := len(tx.journal.entries)
+	err := f()
+
+	if err == nil {
+		return nil
+	}
+
+	partial := tx.journal.entries[mark:]
+	tx.journal.entries = tx.journal.entries[:mark]
+
+	if undoErr := undoJournal(tx.journal.Implementation, partial, tx.set.Provenance); undoErr != nil {
+		return fmt.Errorf("%w; undoing it failed: %s", err, undoErr)
+	}
+
+	return err
+}
+
+func (tx *Transaction) check() error {
+	if tx.done {
+		return ErrTransactionDone
+	}
+
+	if tx.set.writer() != tx.journal {
+		return ErrTransactionOpen
+	}
+
+	return nil
+}
+
+// Commit keeps the changes made in the transaction. If s has a History and
+// this is the outermost transaction, the changes can be undone with Undo.
+func (tx *Transaction) Commit() error {
+	if err := tx.check(); err != nil {
+		return err
+	}
+
+	tx.finish()
+
+	if history := tx.set.History; history != nil && !tx.set.inTransaction() && len(tx.journal.entries) > 0 {
+		history.undo = history.push(history.undo, tx.journal.entries)
+		history.redo = nil
+	}
+
+	return nil
+}
+
+// Rollback reverts every change made in the transaction.
+func (tx *Transaction) Rollback() error {
+	if err := tx.check(); err != nil {
+		return err
+	}
+
+	tx.done = true
+	tx.set.popJournal()
+	defer tx.endBatch()
+
+	return tx.set.observe(
+		func() error {
+			return undoJournal(tx.set.writer(), tx.journal.entries, tx.set.Provenance)
+		},
+	)
+}
+
+func (tx *Transaction) finish() {
+	tx.done = true
+	tx.set.popJournal()
+	tx.endBatch()
+}
+
+func (tx *Transaction) endBatch() {
+	if batcher, ok := tx.set.Implementation.(batcher); ok {
+		batcher.endBatch()
+	}
+}
+
+// History keeps the transactions committed on a Set so they can be undone
+// and redone. Once a Set has a History, every Add and Subtract made outside
+// a transaction is committed as a transaction of its own.
+type History struct {
+	// Limit is the number of transactions kept for Undo, or 0 for no limit.
+	Limit int
+
+	undo [][]journalEntry
+	redo [][]journalEntry
+}
+
+func (h *History) push(stack [][]journalEntry, entries []journalEntry) [][]journalEntry {
+	stack = append(stack, entries)
+
+	if h.Limit > 0 && len(stack) > h.Limit {
+		stack = stack[len(stack)-h.Limit:]
+	}
+
+	return stack
+}
+
+// Undo reverts the last committed transaction that has not been undone.
+func (s *Set) Undo() error {
+	if s.History == nil || len(s.History.undo) == 0 {
+		return ErrNothingToUndo
+	}
+
+	return s.travel(&s.History.undo, &s.History.redo)
+}
+
+// Redo applies the last undone transaction again. Committing a transaction
+// clears what can be redone.
+func (s *Set) Redo() error {
+	if s.History == nil || len(s.History.redo) == 0 {
+		return ErrNothingToRedo
+	}
+
+	return s.travel(&s.History.redo, &s.History.undo)
+}
+
+// travel undoes the newest entries of from and pushes the changes it made
+// to do so onto to, since undoing those again restores the entries.
+func (s *Set) travel(from, to *[][]journalEntry) error {
+	if s.inTransaction() {
+		return ErrTransactionOpen
+	}
+
+	entries := (*from)[len(*from)-1]
+	*from = (*from)[:len(*from)-1]
+
+	return s.observe(
+		func() error {
+			j := &journal{Implementation: s.writer(), provenance: s.Provenance}
+			j.beginBatch()
+			defer j.endBatch()
+
+			if err := undoJournal(j, entries, s.Provenance); err != nil {
+				return err
+			}
+
+			*to = s.History.push(*to, j.entries)
+
+			return nil
+		},
+	)
+}
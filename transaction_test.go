@@ -0,0 +1,212 @@
+package indexset
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionCommitRollback(t *testing.T) {
+	for name, newImplementation := range implementationsToTest(t) {
+		t.Run(name, func(t *testing.T) {
+			s := &Set{Implementation: newImplementation()}
+			assert.Nil(t, s.Add(indexRange{1, 5, 1}))
+
+			tx := s.Begin()
+			assert.Nil(t, tx.Add(indexRange{3, 8, 2}))
+			assert.Nil(t, tx.Subtract(indexRange{1, 1, 1}))
+			assert.Equal(t, []indexRange{{2, 2, 1}, {3, 5, 3}, {6, 8, 2}}, collectIndexRanges(t, s))
+			assert.Nil(t, tx.Rollback())
+			assert.Equal(t, []indexRange{{1, 5, 1}}, collectIndexRanges(t, s))
+
+			tx = s.Begin()
+			assert.Nil(t, tx.Add(indexRange{3, 8, 2}))
+			assert.Nil(t, tx.Commit())
+			assert.Equal(t, []indexRange{{1, 2, 1}, {3, 5, 3}, {6, 8, 2}}, collectIndexRanges(t, s))
+
+			assert.True(t, errors.Is(tx.Commit(), ErrTransactionDone))
+			assert.True(t, errors.Is(tx.Add(indexRange{1, 1, 1}), ErrTransactionDone))
+			assert.Nil(t, s.Validate())
+		})
+	}
+}
+
+func TestTransactionFailedAddIsUndone(t *testing.T) {
+	for name, newImplementation := range implementationsToTest(t) {
+		t.Run(name, func(t *testing.T) {
+			s := &Set{Implementation: newImplementation()}
+			assert.Nil(t, s.Add(indexRange{0, 4, 1}))
+			assert.Nil(t, s.Add(indexRange{5, 9, math.MaxInt64}))
+
+			tx := s.Begin()
+			assert.Nil(t, tx.Add(indexRange{20, 20, 1}))
+
+			//splitting the first segment succeeds before the second overflows
+			err := tx.Add(indexRange{0, 9, 1})
+			assert.True(t, errors.Is(err, ErrWeightOverflow))
+			assert.Equal(t, []indexRange{{0, 4, 1}, {5, 9, math.MaxInt64}, {20, 20, 1}}, collectIndexRanges(t, s))
+
+			assert.Nil(t, tx.Commit())
+			assert.Equal(t, []indexRange{{0, 4, 1}, {5, 9, math.MaxInt64}, {20, 20, 1}}, collectIndexRanges(t, s))
+		})
+	}
+}
+
+func TestTransactionNested(t *testing.T) {
+	s := &Set{Implementation: NewLinkedList()}
+
+	outer := s.Begin()
+	assert.Nil(t, outer.Add(indexRange{1, 5, 1}))
+
+	inner := s.Begin()
+	assert.Nil(t, inner.Add(indexRange{3, 3, 1}))
+	assert.True(t, errors.Is(outer.Commit(), ErrTransactionOpen))
+	assert.True(t, errors.Is(outer.Add(indexRange{1, 1, 1}), ErrTransactionOpen))
+	assert.Nil(t, inner.Rollback())
+
+	assert.Equal(t, []indexRange{{1, 5, 1}}, collectIndexRanges(t, s))
+	assert.Nil(t, outer.Rollback())
+	assert.Equal(t, []indexRange{}, collectIndexRanges(t, s))
+	assert.False(t, s.inTransaction())
+}
+
+func TestTransactionSnapshot(t *testing.T) {
+	s := &Set{Implementation: NewPersistentTree()}
+	assert.Nil(t, s.Add(indexRange{1, 5, 1}))
+
+	tx := s.Begin()
+	assert.Nil(t, tx.Add(indexRange{1, 5, 1}))
+	assert.Equal(t, int64(2), s.Max())
+
+	snapshot, err := s.Snapshot()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), snapshot.Max())
+
+	assert.Nil(t, tx.Commit())
+
+	snapshot, err = s.Snapshot()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), snapshot.Max())
+}
+
+func TestHistory(t *testing.T) {
+	for name, newImplementation := range implementationsToTest(t) {
+		t.Run(name, func(t *testing.T) {
+			s := &Set{Implementation: newImplementation(), History: &History{}}
+			assert.True(t, errors.Is(s.Undo(), ErrNothingToUndo))
+
+			assert.Nil(t, s.Add(indexRange{1, 5, 1}))
+
+			tx := s.Begin()
+			assert.Nil(t, tx.Add(indexRange{3, 8, 2}))
+			assert.Nil(t, tx.Subtract(indexRange{1, 1, 1}))
+			assert.True(t, errors.Is(s.Undo(), ErrTransactionOpen))
+			assert.Nil(t, tx.Commit())
+
+			//a failed Add is not recorded
+			assert.NotNil(t, s.Add(indexRange{2, 2, math.MaxInt64}))
+
+			after := []indexRange{{2, 2, 1}, {3, 5, 3}, {6, 8, 2}}
+			assert.Equal(t, after, collectIndexRanges(t, s))
+
+			assert.Nil(t, s.Undo())
+			assert.Equal(t, []indexRange{{1, 5, 1}}, collectIndexRanges(t, s))
+			assert.Nil(t, s.Undo())
+			assert.Equal(t, []indexRange{}, collectIndexRanges(t, s))
+			assert.True(t, errors.Is(s.Undo(), ErrNothingToUndo))
+
+			assert.Nil(t, s.Redo())
+			assert.Nil(t, s.Redo())
+			assert.Equal(t, after, collectIndexRanges(t, s))
+			assert.True(t, errors.Is(s.Redo(), ErrNothingToRedo))
+
+			assert.Nil(t, s.Undo())
+			assert.Nil(t, s.Add(indexRange{9, 9, 1}))
+			assert.True(t, errors.Is(s.Redo(), ErrNothingToRedo))
+			assert.Equal(t, []indexRange{{1, 5, 1}, {9, 9, 1}}, collectIndexRanges(t, s))
+		})
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	s := &Set{Implementation: NewPersistentTree(), History: &History{Limit: 2}}
+
+	for i := int64(0); i < 4; i++ {
+		assert.Nil(t, s.Add(indexRange{i, i, 1}))
+	}
+
+	assert.Nil(t, s.Undo())
+	assert.Nil(t, s.Undo())
+	assert.True(t, errors.Is(s.Undo(), ErrNothingToUndo))
+	assert.Equal(t, []indexRange{{0, 0, 1}, {1, 1, 1}}, collectIndexRanges(t, s))
+}
+
+func TestHistoryRandom(t *testing.T) {
+	for name, newImplementation := range implementationsToTest(t) {
+		t.Run(name, func(t *testing.T) {
+			random := rand.New(rand.NewSource(1))
+			s := &Set{Implementation: newImplementation(), History: &History{}}
+			states := [][]indexRange{collectIndexRanges(t, s)}
+
+			for i := 0; i < 50; i++ {
+				left := random.Int63n(50)
+				r := indexRange{left, left + random.Int63n(10), random.Int63n(7) - 3}
+
+				if r.weight == 0 {
+					continue
+				}
+
+				assert.Nil(t, s.Add(r))
+				states = append(states, collectIndexRanges(t, s))
+			}
+
+			for i := len(states) - 2; i >= 0; i-- {
+				assert.Nil(t, s.Undo())
+				assert.Equal(t, states[i], collectIndexRanges(t, s))
+				assert.Nil(t, s.Validate())
+			}
+
+			for i := 1; i < len(states); i++ {
+				assert.Nil(t, s.Redo())
+				assert.Equal(t, states[i], collectIndexRanges(t, s))
+			}
+		})
+	}
+}
+
+func TestSnapshotConcurrentWithHistory(t *testing.T) {
+	s := &Set{Implementation: NewPersistentTree(), History: &History{}}
+	count := 100
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < count; i++ {
+			left := int64(i * 10)
+			assert.Nil(t, s.Add(indexRange{left, left + 5, 1}))
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < count; i++ {
+			snapshot, err := s.Snapshot()
+			assert.Nil(t, err)
+			assert.Nil(t, snapshot.Validate())
+		}
+	}()
+
+	wg.Wait()
+
+	snapshot, err := s.Snapshot()
+	assert.Nil(t, err)
+	assert.Len(t, collectIndexRanges(t, snapshot), count)
+}
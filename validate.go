@@ -0,0 +1,32 @@
+package indexset
+
+// segmentChecker verifies that the segments it is fed are non-empty, ordered
+// and do not overlap. Implementations use it both in Validate and while
+// iterating, so that corruption surfaces as an error instead of wrong
+// results.
+type segmentChecker struct {
+	op      string
+	prev    indexRange
+	hasPrev bool
+}
+
+func (c *segmentChecker) check(current indexRange) error {
+	if current.left > current.right {
+		return &RangeError{Op: c.op, Ranges: []indexRange{current}, Err: ErrEmptySegment}
+	}
+
+	if c.hasPrev {
+		if current.left < c.prev.left {
+			return &RangeError{Op: c.op, Ranges: []indexRange{c.prev, current}, Err: ErrOutOfOrder}
+		}
+
+		if current.left <= c.prev.right {
+			return &RangeError{Op: c.op, Ranges: []indexRange{c.prev, current}, Err: ErrOverlap}
+		}
+	}
+
+	c.prev = current
+	c.hasPrev = true
+
+	return nil
+}
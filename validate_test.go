@@ -0,0 +1,153 @@
+package indexset
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func makeLinkedList(ranges ...indexRange) *linkedList {
+	l := &linkedList{}
+	var prev *node
+
+	for _, r := range ranges {
+		n := &node{indexRange: r, prev: prev}
+
+		if prev == nil {
+			l.head = n
+		} else {
+			prev.next = n
+		}
+
+		prev = n
+	}
+
+	return l
+}
+
+type validateTestCase struct {
+	description string
+	indexRanges []indexRange
+	expected    error
+}
+
+func validateTestCases() []validateTestCase {
+	return []validateTestCase{
+		{
+			description: "empty",
+			expected:    nil,
+		},
+		{
+			description: "ordered",
+			indexRanges: []indexRange{{1, 2, 1}, {3, 3, 2}, {7, 9, 1}},
+			expected:    nil,
+		},
+		{
+			description: "out of order",
+			indexRanges: []indexRange{{5, 6, 1}, {1, 2, 1}},
+			expected:    ErrOutOfOrder,
+		},
+		{
+			description: "overlap",
+			indexRanges: []indexRange{{1, 5, 1}, {5, 6, 1}},
+			expected:    ErrOverlap,
+		},
+		{
+			description: "zero length",
+			indexRanges: []indexRange{{1, 2, 1}, {6, 5, 1}},
+			expected:    ErrEmptySegment,
+		},
+	}
+}
+
+func TestValidateLinkedList(t *testing.T) {
+	for _, test := range validateTestCases() {
+		t.Run(
+			test.description,
+			func(t *testing.T) {
+				l := makeLinkedList(test.indexRanges...)
+
+				err := l.Validate()
+				assert.True(t, errors.Is(err, test.expected), "%v", err)
+
+				err = l.Do(func(Member) bool { return false })
+				assert.True(t, errors.Is(err, test.expected), "%v", err)
+			},
+		)
+	}
+}
+
+func TestValidateLinkedListLinks(t *testing.T) {
+	l := makeLinkedList(indexRange{1, 2, 1}, indexRange{4, 5, 1}, indexRange{7, 8, 1})
+	l.head.next.next.prev = l.head
+
+	err := l.Validate()
+	assert.True(t, errors.Is(err, ErrBrokenLink))
+	assert.True(t, errors.Is(err, ErrCorrupt))
+}
+
+func TestValidatePersistentTree(t *testing.T) {
+	for _, test := range validateTestCases() {
+		t.Run(
+			test.description,
+			func(t *testing.T) {
+				tree := &persistentTree{}
+
+				for _, r := range test.indexRanges {
+					tree.setRoot(tree.root.insert(r))
+				}
+
+				if test.expected == ErrOutOfOrder {
+					//insertion always keeps the tree ordered, so build it by hand
+					tree.setRoot(makeTreeNode(test.indexRanges[0], nil, makeTreeNode(test.indexRanges[1], nil, nil)))
+				}
+
+				err := tree.Validate()
+				assert.True(t, errors.Is(err, test.expected), "%v", err)
+
+				err = tree.Do(func(Member) bool { return false })
+				assert.True(t, errors.Is(err, test.expected), "%v", err)
+			},
+		)
+	}
+}
+
+func TestValidatePersistentTreeNodes(t *testing.T) {
+	tree := &persistentTree{}
+
+	for i := int64(0); i < 10; i++ {
+		tree.setRoot(tree.root.insert(indexRange{i * 2, i*2 + 1, i}))
+	}
+
+	assert.Nil(t, tree.Validate())
+
+	tree.root.after.maxWeight = 100
+
+	err := tree.Validate()
+	assert.True(t, errors.Is(err, ErrUnbalanced))
+	assert.Nil(t, tree.Do(func(Member) bool { return false }))
+}
+
+func TestValidateShardedSet(t *testing.T) {
+	set, err := NewShardedSet(19, 2, NewPersistentTree)
+	assert.Nil(t, err)
+	assert.Nil(t, set.Add(indexRange{5, 15, 1}))
+	assert.Nil(t, set.Validate())
+
+	_, err = set.shards[0].set.AddOrFindOverlapping(indexRange{12, 13, 1})
+	assert.Nil(t, err)
+	assert.True(t, errors.Is(set.Validate(), ErrOutOfOrder))
+}
+
+func TestReadsStopAtCorruption(t *testing.T) {
+	set := &Set{Implementation: makeLinkedList(indexRange{1, 2, 1}, indexRange{6, 7, 5}, indexRange{3, 4, 9})}
+
+	//every read answers from the segments before the one out of order
+	assert.Equal(t, int64(5), set.Max())
+	assert.Equal(t, indexRange{6, 7, 5}, set.Nth(1).IndexRange())
+	assert.Nil(t, set.Nth(2))
+	assert.Len(t, set.FindOverlapping(indexRange{0, 10, 0}), 2)
+	assert.Contains(t, set.String(), ErrOutOfOrder.Error())
+	assert.True(t, errors.Is(set.Validate(), ErrOutOfOrder))
+}
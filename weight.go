@@ -0,0 +1,36 @@
+package indexset
+
+import (
+	"math"
+)
+
+// WeightArithmetic selects what happens when summing the weights of
+// overlapping ranges would overflow an int64.
+type WeightArithmetic int
+
+const (
+	// WeightArithmeticChecked fails the split with ErrWeightOverflow.
+	WeightArithmeticChecked = WeightArithmetic(iota)
+	// WeightArithmeticSaturating clamps the sum to math.MaxInt64 or
+	// math.MinInt64.
+	WeightArithmeticSaturating
+)
+
+func (w WeightArithmetic) add(a, b indexRange) (int64, error) {
+	switch {
+	case b.weight > 0 && a.weight > math.MaxInt64-b.weight:
+		if w == WeightArithmeticSaturating {
+			return math.MaxInt64, nil
+		}
+
+	case b.weight < 0 && a.weight < math.MinInt64-b.weight:
+		if w == WeightArithmeticSaturating {
+			return math.MinInt64, nil
+		}
+
+	default:
+		return a.weight + b.weight, nil
+	}
+
+	return 0, &RangeError{Op: "split", Ranges: []indexRange{a, b}, Err: ErrWeightOverflow}
+}
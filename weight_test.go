@@ -0,0 +1,89 @@
+package indexset
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type weightOverflowTestCase struct {
+	description string
+	a           indexRange
+	b           indexRange
+	saturated   int64
+}
+
+func weightOverflowTestCases() []weightOverflowTestCase {
+	return []weightOverflowTestCase{
+		{"equal", indexRange{1, 5, math.MaxInt64}, indexRange{1, 5, 1}, math.MaxInt64},
+		{"inside", indexRange{1, 5, math.MaxInt64}, indexRange{2, 3, 1}, math.MaxInt64},
+		{"inside left", indexRange{1, 5, math.MaxInt64}, indexRange{1, 3, 1}, math.MaxInt64},
+		{"inside right", indexRange{1, 5, math.MaxInt64}, indexRange{3, 5, 1}, math.MaxInt64},
+		{"outside right", indexRange{1, 5, math.MaxInt64}, indexRange{4, 8, 1}, math.MaxInt64},
+		{"outside right, primary", indexRange{4, 8, 1}, indexRange{1, 5, math.MaxInt64}, math.MaxInt64},
+		{"negative", indexRange{1, 5, math.MinInt64}, indexRange{1, 5, -1}, math.MinInt64},
+	}
+}
+
+func TestWeightOverflowChecked(t *testing.T) {
+	for _, test := range weightOverflowTestCases() {
+		t.Run(
+			test.description,
+			func(t *testing.T) {
+				_, _, err := test.a.SplitWith(test.b)
+				assert.True(t, errors.Is(err, ErrWeightOverflow), "%v", err)
+
+				var rangeError *RangeError
+				assert.True(t, errors.As(err, &rangeError))
+			},
+		)
+	}
+}
+
+func TestWeightOverflowSaturating(t *testing.T) {
+	for _, test := range weightOverflowTestCases() {
+		t.Run(
+			test.description,
+			func(t *testing.T) {
+				replacements, _, err := test.a.splitWith(test.b, WeightArithmeticSaturating)
+				assert.Nil(t, err)
+
+				saturated := false
+
+				for _, r := range replacements {
+					if r.weight == test.saturated {
+						saturated = true
+					}
+				}
+
+				assert.True(t, saturated, "%v", replacements)
+			},
+		)
+	}
+}
+
+func TestSetAddWeightOverflow(t *testing.T) {
+	for implementationName, implementation := range implementationsToTest(t) {
+		t.Run(
+			implementationName,
+			func(t *testing.T) {
+				set := &Set{Implementation: implementation()}
+				assert.Nil(t, set.Add(indexRange{1, 5, math.MaxInt64 - 1}))
+				assert.Nil(t, set.Add(indexRange{1, 5, 1}))
+				assert.True(t, errors.Is(set.Add(indexRange{1, 5, 1}), ErrWeightOverflow))
+				assert.Equal(t, int64(math.MaxInt64), set.Max())
+
+				saturating := &Set{
+					Implementation: implementation(),
+					Arithmetic:     WeightArithmeticSaturating,
+				}
+
+				assert.Nil(t, saturating.Add(indexRange{1, 5, math.MaxInt64}))
+				assert.Nil(t, saturating.Add(indexRange{2, 3, math.MaxInt64}))
+				assert.Equal(t, int64(math.MaxInt64), saturating.Max())
+			},
+		)
+	}
+}
@@ -0,0 +1,100 @@
+//go:generate stringer -type=Workload -trimprefix=Workload
+
+package indexset
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Workload selects the shape of the ranges produced by GenerateWorkload.
+type Workload int
+
+const (
+	// WorkloadUniform spreads short ranges uniformly over the coordinates.
+	WorkloadUniform = Workload(iota)
+	// WorkloadClustered concentrates ranges around a few hot spots.
+	WorkloadClustered
+	// WorkloadNested centers every range on the same point so they contain
+	// each other.
+	WorkloadNested
+	// WorkloadLongTail draws widths from a heavy tailed distribution.
+	WorkloadLongTail
+	// WorkloadSortedInsert adds ranges in increasing order of left.
+	WorkloadSortedInsert
+)
+
+func Workloads() []Workload {
+	return []Workload{
+		WorkloadUniform,
+		WorkloadClustered,
+		WorkloadNested,
+		WorkloadLongTail,
+		WorkloadSortedInsert,
+	}
+}
+
+const (
+	workloadMaxWidth = 100
+	workloadClusters = 8
+)
+
+// GenerateWorkload returns count ranges over [0, 10*count) with weights
+// between 1 and 10. The same seed always produces the same ranges.
+func GenerateWorkload(workload Workload, count int, seed int64) []indexRange {
+	random := rand.New(rand.NewSource(seed))
+	span := int64(count)*10 + workloadMaxWidth
+	ranges := make([]indexRange, count)
+
+	clusters := make([]int64, workloadClusters)
+
+	for i := range clusters {
+		clusters[i] = random.Int63n(span)
+	}
+
+	for i := range ranges {
+		var left, width int64
+
+		switch workload {
+		case WorkloadClustered:
+			center := clusters[random.Intn(len(clusters))]
+			left = center + int64(random.NormFloat64()*float64(workloadMaxWidth))
+			width = random.Int63n(workloadMaxWidth) + 1
+
+		case WorkloadNested:
+			width = random.Int63n(span/2) + 1
+			left = span/2 - width/2
+
+		case WorkloadLongTail:
+			//pareto distributed with alpha 1.2
+			width = int64(math.Min(float64(span), 1/math.Pow(1-random.Float64(), 1/1.2)))
+			left = random.Int63n(span)
+
+		default:
+			left = random.Int63n(span)
+			width = random.Int63n(workloadMaxWidth) + 1
+		}
+
+		if left < 0 {
+			left = 0
+		}
+
+		ranges[i] = indexRange{
+			left:   left,
+			right:  left + width - 1,
+			weight: random.Int63n(10) + 1,
+		}
+	}
+
+	if workload == WorkloadSortedInsert {
+		sort.Slice(
+			ranges,
+			func(i, j int) bool {
+				return ranges[i].lessThan(ranges[j])
+			},
+		)
+	}
+
+	return ranges
+}
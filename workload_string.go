@@ -0,0 +1,28 @@
+// Code generated by "stringer -type=Workload -trimprefix=Workload"; DO NOT EDIT.
+
+package indexset
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[WorkloadUniform-0]
+	_ = x[WorkloadClustered-1]
+	_ = x[WorkloadNested-2]
+	_ = x[WorkloadLongTail-3]
+	_ = x[WorkloadSortedInsert-4]
+}
+
+const _Workload_name = "UniformClusteredNestedLongTailSortedInsert"
+
+var _Workload_index = [...]uint8{0, 7, 16, 22, 30, 42}
+
+func (i Workload) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_Workload_index)-1 {
+		return "Workload(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _Workload_name[_Workload_index[idx]:_Workload_index[idx+1]]
+}
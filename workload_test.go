@@ -0,0 +1,31 @@
+package indexset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateWorkload(t *testing.T) {
+	for _, workload := range Workloads() {
+		t.Run(
+			workload.String(),
+			func(t *testing.T) {
+				ranges := GenerateWorkload(workload, 200, 7)
+				assert.Len(t, ranges, 200)
+				assert.Equal(t, ranges, GenerateWorkload(workload, 200, 7))
+
+				for _, r := range ranges {
+					_, err := MakeRange(r.left, r.right, r.weight)
+					assert.Nil(t, err)
+				}
+
+				if workload == WorkloadSortedInsert {
+					for i := 1; i < len(ranges); i++ {
+						assert.False(t, ranges[i].lessThan(ranges[i-1]))
+					}
+				}
+			},
+		)
+	}
+}